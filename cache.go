@@ -0,0 +1,96 @@
+package oom
+
+// cache.go manages the files competition.go caches pages and results in:
+// KEY.txt for each competition's results and all_comps_YEAR.dat for each
+// year's list of competitions.  They are kept in the Client's CacheDir,
+// along with the pages they were parsed from, see pages.go, which are
+// removed with them
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// CacheFile describes a cached file
+type CacheFile struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Year    int          // for a list of competitions, otherwise 0
+	Comp    *Competition // for a competition's results, otherwise nil
+	Page    *PageInfo    // the page it was parsed from, if kept
+
+	pagePaths []string // the files the page is kept in
+}
+
+var (
+	compsFileRE   = regexp.MustCompile(`^all_comps_(\d+)\.dat$`)
+	resultsFileRE = regexp.MustCompile(`^(\d+)\.txt$`)
+)
+
+// ReadCache returns the cached lists of competitions, by year, followed by
+// the cached competition results, in date order
+func (c *Client) ReadCache() ([]CacheFile, error) {
+	dir := c.CacheDir
+	if dir == "" {
+		dir = "."
+	}
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var lists, results []CacheFile
+	for _, fi := range fis {
+		f := CacheFile{Path: c.cachePath(fi.Name()), Size: fi.Size(), ModTime: fi.ModTime()}
+		if m := compsFileRE.FindStringSubmatch(fi.Name()); m != nil {
+			f.Year, _ = strconv.Atoi(m[1])
+			c.addPage(&f, allCompsURL(f.Year))
+			lists = append(lists, f)
+		} else if m := resultsFileRE.FindStringSubmatch(fi.Name()); m != nil {
+			comp := Competition{Key: m[1]}
+			if ok, err := c.LoadCached(&comp); err != nil {
+				Logger.Warn("skipping malformed cached file", "file", f.Path, "err", err)
+				continue
+			} else if !ok {
+				continue
+			}
+			f.Comp = &comp
+			c.addPage(&f, comp.URL)
+			results = append(results, f)
+		}
+	}
+	sort.Slice(lists, func(i, j int) bool { return lists[i].Year < lists[j].Year })
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Comp.Time().Before(results[j].Comp.Time())
+	})
+	return append(lists, results...), nil
+}
+
+// addPage notes the page from urlString that f was parsed from
+func (c *Client) addPage(f *CacheFile, urlString string) {
+	if urlString == "" {
+		return
+	}
+	if p, ok := c.Page(urlString); ok {
+		f.Page = &p
+	}
+	f.pagePaths = c.pagePaths(urlString)
+}
+
+// Remove deletes the cached file and the page it was parsed from, so both
+// are fetched again
+func (f CacheFile) Remove() error {
+	if err := os.Remove(f.Path); err != nil {
+		return err
+	}
+	for _, path := range f.pagePaths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
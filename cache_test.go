@@ -0,0 +1,73 @@
+package oom
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadCache(t *testing.T) {
+	c := newTestClient(t)
+	dir := c.CacheDir
+
+	comp := testComp("12", "Sat 9th Apr '16", "Ann", "Bea")
+	c.saveComp(&comp)
+	comp = testComp("3", "Sat 2nd Apr '16", "Cat")
+	comp.URL = "https://example.com/competition.php?compid=3"
+	c.saveComp(&comp)
+	c.keepPage(comp.URL, response{body: []byte("Cat 36")})
+	c.keepPage(comp.URL, response{body: []byte("Cat 37")})
+	for _, fname := range []string{"all_comps_2016.dat", "notes.txt", "2024.txt"} {
+		ioutil.WriteFile(filepath.Join(dir, fname), []byte("x"), 0644)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "555.txt"), nil, 0644)
+
+	files, err := c.ReadCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 { // 2024.txt and 555.txt skipped as malformed
+		t.Fatalf("expected 3 cached files, got %v", files)
+	}
+	if files[0].Year != 2016 || files[0].Comp != nil {
+		t.Errorf("expected the 2016 list of competitions first, got %v", files[0])
+	}
+	if files[1].Comp == nil || files[1].Comp.Key != "3" || files[2].Comp.Key != "12" {
+		t.Errorf("expected competitions 3 then 12, got %v", files[1:])
+	}
+	if r := files[2].Comp.Results["Bea"]; r.Rank != 2 || r.OOMPoints != 1 {
+		t.Errorf("expected Bea 2nd with 1 point, got %v", r)
+	}
+	if p := files[1].Page; p == nil || !p.Amended() || files[2].Page != nil {
+		t.Errorf("expected only competition 3's page kept, and amended, got %v and %v", p, files[2].Page)
+	}
+	if err := files[1].Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := c.LoadCached(&Competition{Key: "3"}); ok {
+		t.Error("expected competition 3 to be removed")
+	}
+	if _, ok := c.Page("https://example.com/competition.php?compid=3"); ok {
+		t.Error("expected competition 3's page to be removed")
+	}
+}
+
+func TestReadCachedMalformed(t *testing.T) {
+	c := newTestClient(t)
+	for key, content := range map[string]string{
+		"1": "",
+		"2": "a note\n",
+		"3": "# comments only\n",
+		"4": "key, 4\nname, comp 4\ndate, Sat 2nd Apr '16\n",
+		"5": "key, 5\nname, comp 5\ndate, Sat 2nd Apr '16\nurl, \nnumber of players, 1\nheader\n3, 1\n",
+	} {
+		ioutil.WriteFile(filepath.Join(c.CacheDir, key+".txt"), []byte(content), 0644)
+		comp := Competition{Key: key}
+		if ok, err := c.LoadCached(&comp); ok || err == nil {
+			t.Errorf("%s: expected an error, got %v %v", key, ok, err)
+		}
+		if err := c.Load(&comp); err == nil || err == ErrNotCached {
+			t.Errorf("%s: expected Load to report the file, got %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package oom
+
+// chart.go draws charts of the season for the presentation night, as SVG
+// built by hand so they need nothing but a browser to view:
+// - rank after each competition for the top players
+// - cumulative points after each competition for the top players
+// - field size per competition
+// The rank and points charts are taken from the standings history, the
+// table recalculated after each competition in date order.  WriteHTML
+// embeds all three in charts.html
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// ChartOptions controls the size and content of the charts
+type ChartOptions struct {
+	Top    int // players drawn on the rank and points charts, default 10
+	Width  int // default 800
+	Height int // default 400
+}
+
+func (o ChartOptions) withDefaults() ChartOptions {
+	if o.Top == 0 {
+		o.Top = 10
+	}
+	if o.Width == 0 {
+		o.Width = 800
+	}
+	if o.Height == 0 {
+		o.Height = 400
+	}
+	return o
+}
+
+// margins around the plot, leaving room for the title, axis labels and
+// the legend on the right
+const (
+	chartLeft   = 50
+	chartRight  = 170
+	chartTop    = 40
+	chartBottom = 110
+)
+
+var chartColours = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+	"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"}
+
+// chart is a line chart of series or a bar chart, a point or bar per
+// competition
+type chart struct {
+	title  string
+	opts   ChartOptions
+	comps  []Competition // x axis, in date order
+	series []chartSeries
+	bars   []float64
+	yMin   float64
+	yMax   float64
+	invert bool   // y increases down the page, as for ranks
+	unit   string // singular, for tooltips
+}
+
+// chartSeries is a player's line, NaN where they have no value
+type chartSeries struct {
+	name   string
+	values []float64
+}
+
+// WriteRankChart writes the rank of the top players after each competition
+func (s *Standings) WriteRankChart(w io.Writer, opts ChartOptions) error {
+	return s.rankChart(s.History(), opts).write(w)
+}
+
+// WritePointsChart writes the cumulative points of the top players after
+// each competition
+func (s *Standings) WritePointsChart(w io.Writer, opts ChartOptions) error {
+	return s.pointsChart(s.History(), opts).write(w)
+}
+
+// WriteFieldChart writes the number of players in each competition
+func (s *Standings) WriteFieldChart(w io.Writer, opts ChartOptions) error {
+	return s.fieldChart(opts).write(w)
+}
+
+// topPlayers returns the names of the top n ranked players
+func (s *Standings) topPlayers(n int) []string {
+	if len(s.RankedPlayers) > n {
+		return s.RankedPlayers[:n]
+	}
+	return s.RankedPlayers
+}
+
+func (s *Standings) rankChart(history []*Standings, opts ChartOptions) *chart {
+	opts = opts.withDefaults()
+	c := &chart{title: fmt.Sprintf("Rank - top %d", opts.Top), opts: opts,
+		comps: s.byDate(), yMin: 1, yMax: 1, invert: true}
+	for _, name := range s.topPlayers(opts.Top) {
+		sr := chartSeries{name: name}
+		for _, after := range history {
+			v := math.NaN()
+			if p, ok := after.OOMResults[name]; ok && !p.Provisional {
+				v = float64(p.Rank)
+				c.yMax = math.Max(c.yMax, v)
+			}
+			sr.values = append(sr.values, v)
+		}
+		c.series = append(c.series, sr)
+	}
+	return c
+}
+
+func (s *Standings) pointsChart(history []*Standings, opts ChartOptions) *chart {
+	opts = opts.withDefaults()
+	c := &chart{title: fmt.Sprintf("Points - top %d", opts.Top), opts: opts,
+		comps: s.byDate(), unit: "point"}
+	for _, name := range s.topPlayers(opts.Top) {
+		sr := chartSeries{name: name}
+		for _, after := range history {
+			v := math.NaN()
+			if p, ok := after.OOMResults[name]; ok {
+				v = float64(p.OOMPoints)
+				c.yMax = math.Max(c.yMax, v)
+			}
+			sr.values = append(sr.values, v)
+		}
+		c.series = append(c.series, sr)
+	}
+	return c
+}
+
+func (s *Standings) fieldChart(opts ChartOptions) *chart {
+	c := &chart{title: "Field size", opts: opts.withDefaults(), comps: s.byDate(),
+		unit: "player"}
+	for _, comp := range c.comps {
+		c.bars = append(c.bars, float64(comp.NumPlayers))
+		c.yMax = math.Max(c.yMax, float64(comp.NumPlayers))
+	}
+	return c
+}
+
+// x returns the centre of the i'th competition's slot
+func (c *chart) x(i int) float64 {
+	width := float64(c.opts.Width - chartLeft - chartRight)
+	return chartLeft + width*(float64(i)+0.5)/float64(len(c.comps))
+}
+
+func (c *chart) y(v float64) float64 {
+	height := float64(c.opts.Height - chartTop - chartBottom)
+	f := (v - c.yMin) / (c.yMax - c.yMin)
+	if c.invert {
+		return chartTop + height*f
+	}
+	return chartTop + height*(1-f)
+}
+
+func (c *chart) write(w io.Writer) error {
+	if c.yMax <= c.yMin {
+		c.yMax = c.yMin + 1
+	}
+	width, height := c.opts.Width, c.opts.Height
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(&b, "<title>%s</title>\n", xmlEscape(c.title))
+	fmt.Fprintf(&b, `<text x="%d" y="20" font-size="16" text-anchor="middle">%s</text>`+"\n",
+		(width-chartRight+chartLeft)/2, xmlEscape(c.title))
+
+	// y axis grid and labels
+	step := niceStep(c.yMax - c.yMin)
+	for v := c.yMin; v <= c.yMax; v = math.Floor(v/step)*step + step {
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ddd"/>`+"\n",
+			chartLeft, c.y(v), width-chartRight, c.y(v))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%g</text>`+"\n",
+			chartLeft-6, c.y(v), v)
+	}
+	base := float64(height - chartBottom)
+	fmt.Fprintf(&b, `<line x1="%d" y1="%g" x2="%d" y2="%g" stroke="#222"/>`+"\n",
+		chartLeft, base, width-chartRight, base)
+
+	// x axis labels, a competition each
+	for i, comp := range c.comps {
+		fmt.Fprintf(&b, `<text transform="translate(%.1f,%g) rotate(-45)" text-anchor="end">%s<title>%s (%s)</title></text>`+"\n",
+			c.x(i), base+14, xmlEscape(truncate(comp.Name, 20)), xmlEscape(comp.Name), xmlEscape(comp.Date))
+	}
+
+	if len(c.comps) > 0 {
+		barWidth := 0.6 * (c.x(1) - c.x(0))
+		if len(c.comps) == 1 {
+			barWidth = 0.6 * float64(width-chartLeft-chartRight)
+		}
+		for i, v := range c.bars {
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="#1f5130"><title>%s: %s</title></rect>`+"\n",
+				c.x(i)-barWidth/2, c.y(v), barWidth, base-c.y(v), xmlEscape(c.comps[i].Name), plural(int(v), c.unit))
+		}
+	}
+
+	for n, sr := range c.series {
+		colour := chartColours[n%len(chartColours)]
+		// a polyline per run of competitions the player has a value for
+		var points []string
+		line := func() {
+			if len(points) > 1 {
+				fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`+"\n",
+					colour, strings.Join(points, " "))
+			}
+			points = nil
+		}
+		for i, v := range sr.values {
+			if math.IsNaN(v) {
+				line()
+				continue
+			}
+			points = append(points, fmt.Sprintf("%.1f,%.1f", c.x(i), c.y(v)))
+			label := fmt.Sprintf("%s: %g", sr.name, v)
+			if c.unit != "" {
+				label = sr.name + ": " + plural(int(v), c.unit)
+			}
+			fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>%s after %s</title></circle>`+"\n",
+				c.x(i), c.y(v), colour, xmlEscape(label), xmlEscape(c.comps[i].Name))
+		}
+		line()
+		// legend
+		ly := chartTop + 16*n
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="12" height="12" fill="%s"/>`+"\n",
+			width-chartRight+12, ly, colour)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" dominant-baseline="middle">%s</text>`+"\n",
+			width-chartRight+30, ly+6, xmlEscape(truncate(sr.name, 22)))
+	}
+	b.WriteString("</svg>\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// niceStep returns a 1, 2 or 5 times power of 10 step giving about 5
+// gridlines over span, but no less than 1
+func niceStep(span float64) float64 {
+	raw := span / 5
+	if raw <= 1 {
+		return 1
+	}
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5} {
+		if m*mag >= raw {
+			return m * mag
+		}
+	}
+	return 10 * mag
+}
+
+// truncate shortens s to n runes, ending with an ellipsis if shortened
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
@@ -0,0 +1,58 @@
+package oom
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestCharts(t *testing.T) {
+	comps := []Competition{
+		testComp("2", "Sat 9th Apr '16", "Bea", "Cat"),
+		testComp("1", "Sat 2nd Apr '16", "Ann & Co", "Bea"),
+	}
+	s := NewStandings(2016, comps, Options{MaxComps: 10})
+	for name, write := range map[string]func(*bytes.Buffer) error{
+		"rank":   func(b *bytes.Buffer) error { return s.WriteRankChart(b, ChartOptions{Top: 3}) },
+		"points": func(b *bytes.Buffer) error { return s.WritePointsChart(b, ChartOptions{Top: 3}) },
+		"field":  func(b *bytes.Buffer) error { return s.WriteFieldChart(b, ChartOptions{}) },
+	} {
+		var b bytes.Buffer
+		if err := write(&b); err != nil {
+			t.Fatal(err)
+		}
+		svg := b.String()
+		// well formed
+		d := xml.NewDecoder(strings.NewReader(svg))
+		for {
+			if _, err := d.Token(); err != nil {
+				if err.Error() != "EOF" {
+					t.Errorf("%s: %v in\n%s", name, err, svg)
+				}
+				break
+			}
+		}
+		if !strings.Contains(svg, "<title>comp 1 (Sat 2nd Apr &#39;16)</title>") {
+			t.Errorf("%s: expected competitions in date order in\n%s", name, svg)
+		}
+		if name == "field" {
+			if n := strings.Count(svg, "<rect"); n != 2 {
+				t.Errorf("field: expected 2 bars, got %d", n)
+			}
+			continue
+		}
+		// lines for Ann and Bea, a point for Cat who only played comp 2
+		if strings.Count(svg, "<polyline") != 2 || strings.Count(svg, "<circle") != 5 {
+			t.Errorf("%s: expected 2 lines and 5 points in\n%s", name, svg)
+		}
+	}
+}
+
+func TestNiceStep(t *testing.T) {
+	for span, want := range map[float64]float64{3: 1, 9: 2, 24: 5, 40: 10, 130: 50} {
+		if got := niceStep(span); got != want {
+			t.Errorf("niceStep(%g): expected %g, got %g", span, want, got)
+		}
+	}
+}
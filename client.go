@@ -0,0 +1,74 @@
+package oom
+
+// client.go has the Client, which holds the settings and the state of
+// loading competitions - where they are cached, how the website is logged
+// in to and how requests to it are made - so the package keeps nothing in
+// package variables and two clients, say in tests, don't interfere
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Client loads competitions from the files cached in CacheDir or from the
+// website, logged in with credentials found as described in creds.go.
+// Change its settings before it is first used
+type Client struct {
+	CacheDir string // directory of the cached files, "" for the current directory
+	Offline  bool   // never fetch from the website, using only the cached files
+
+	CredsFile          string // email and pin on two lines in plain text
+	NetrcFile          string // netrc file with the website's machine entry, "" for none
+	EncryptedCredsFile string // credentials saved by SaveEncryptedCreds
+	SessionFile        string // session cookies kept between runs, "" to not keep them
+
+	UserAgent       string        // identifies the requests to the website
+	Timeout         time.Duration // limits each request, including reading the page
+	Retries         int           // times a failed request is retried
+	Backoff         time.Duration // delay before the first retry, doubling after
+	RequestInterval time.Duration // least time between the starts of requests to a host
+
+	loginURL string // the website's login page, changed by the tests
+
+	mutex    sync.Mutex   // guards http and the login
+	http     *http.Client // created on first use, with the saved session
+	loginGen int          // counts the login attempts, see relogin
+	loginErr error        // of the last login attempt
+
+	hostMutex   sync.Mutex           // guards nextRequest
+	nextRequest map[string]time.Time // earliest time of the next request to each host
+
+	pagesMutex sync.Mutex // guards the pages kept, see pages.go
+}
+
+// NewClient returns a client with the default settings: the cache in the
+// current directory, credentials in creds.conf, creds.enc or ~/.netrc, if
+// there's a home directory, and a request to the website every 500ms at
+// most
+func NewClient() *Client {
+	netrc := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		netrc = filepath.Join(home, ".netrc")
+	}
+	return &Client{
+		CredsFile:          "creds.conf",
+		NetrcFile:          netrc,
+		EncryptedCredsFile: "creds.enc",
+		SessionFile:        ".oom-session",
+		UserAgent:          "oom/1.0 (ladies order of merit)",
+		Timeout:            30 * time.Second,
+		Retries:            3,
+		Backoff:            2 * time.Second,
+		RequestInterval:    500 * time.Millisecond,
+		loginURL:           defaultLoginURL,
+		nextRequest:        make(map[string]time.Time),
+	}
+}
+
+// cachePath returns the path of the cached file fname
+func (c *Client) cachePath(fname string) string {
+	return filepath.Join(c.CacheDir, fname)
+}
@@ -5,7 +5,8 @@ package oom
 // website if required - and caches it locally in files so that subsequent
 // runs can be completed 'off-line'
 //
-// The set of files consulted and generated as follows:
+// The set of files consulted and generated as follows, the cached files
+// being kept in the Client's CacheDir (see cache.go):
 // - 5462.txt caches results of competition with key of 5462 in human readable
 //   and editable form.  Results of match play following certain stroke play
 //   compeitions will be used to manually update the relevant result file.
@@ -18,39 +19,111 @@ package oom
 //   optionally full URL of each competition of interest.
 //   As a minimum each line contains "cystic fibrosis, ?compid=1239" where
 //   the ?compid=1239 contains the (vital) competition key and may be
-//   part of a full URL as copied from the website
+//   part of a full URL as copied from the website.  A further field
+//   "major" marks the competition as a major e.g.
+//   "EGU Gold Medal, ?compid=1268, major"
+//   The first field is kept as the competition's Label, naming it in
+//   reports of missing competitions before its name is known.
+//   Lines of the form "match, Ladies.*Medal" add every competition in the
+//   year whose name matches the case insensitive regular expression (which
+//   can't contain a comma), again optionally followed by "major" - so new
+//   competitions in the series are included without listing their keys
+//
+// Setting the Client's Offline stops all fetching from the website: only
+// the cached files are used and Load returns ErrNotCached for a
+// competition that isn't cached
 
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"io/ioutil"
-	"log"
 	"os"
+	"path/filepath"
 	"fmt"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"net/url"
+	"time"
 )
 
+// ErrNotCached is returned by Load when Offline and the competition is not
+// cached
+var ErrNotCached = errors.New("competition not cached")
+
 // PlayerResult represents how a single player scored in a single competition
 type PlayerResult struct {
-	Name      string // used as key
-	OOMPoints int
-	Rank      int
-	Result    string // stableford, gross, net, or bogey result as displayed on web
+	Name      string `json:"name"`         // used as key
+	ID        string `json:"id,omitempty"` // playerid on the website, empty if not known
+	OOMPoints int    `json:"oomPoints"`
+	Rank      int    `json:"rank"`
+	Result    string `json:"result"`            // stableford, gross, net, or bogey result as displayed on web
+	Counted   bool   `json:"counted,omitempty"` // set in PlayerOOM.PlayerByComp if OOMPoints count to the total
 }
 
 // Competition describes the competition and all the players results
 type Competition struct {
 	// The first set of fields can be parsed from the 'list of comps' webpage
-	Key        string
-	Name       string
-	Date       string
-	URL        string
+	Key   string `json:"key"`
+	Name  string `json:"name"`
+	Date  string `json:"date"`
+	URL   string `json:"url"`
+	Major bool   `json:"major,omitempty"` // marked major in oom.conf, see Options.MinMajors
+	Label string `json:"label,omitempty"` // the name given in oom.conf
 	// The remaining fields can be populated from the web page for this competition
-	NumPlayers int
-	Results    map[string]PlayerResult // key by player name
+	NumPlayers int                     `json:"numPlayers"`
+	Results    map[string]PlayerResult `json:"results"` // key by player name
+	// Overrides names the players whose cached results were edited by hand,
+	// by "# override: NAME" lines at the top of the cached file, which
+	// Verification.Apply leaves alone
+	Overrides []string `json:"overrides,omitempty"`
+	notes     []string // the other comment lines at the top of the cached file
+}
+
+// Time returns the competition date, parsed from the website format
+// e.g. "Fri 25th Mar '16", or the zero time if it cannot be parsed
+func (c Competition) Time() time.Time {
+	f := strings.Fields(c.Date)
+	if len(f) != 4 {
+		return time.Time{}
+	}
+	day := strings.TrimRight(f[1], "stndrh")
+	t, err := time.Parse("2 Jan '06", day+" "+f[2]+" "+f[3])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// Title returns the competition's name, or its label from oom.conf if the
+// name isn't known, followed by its key e.g. "elmstead (1266)"
+func (c Competition) Title() string {
+	name := c.Name
+	if name == "" {
+		name = c.Label
+	}
+	if name == "" {
+		return c.Key
+	}
+	return name + " (" + c.Key + ")"
+}
+
+// byRank returns the results in finishing order
+func (c Competition) byRank() []PlayerResult {
+	var ret []PlayerResult
+	for _, r := range c.Results {
+		ret = append(ret, r)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Rank != ret[j].Rank {
+			return ret[i].Rank < ret[j].Rank
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
 }
 
 // FIRST SECTION OF FILE DEALS WITH BUILDING LIST OF COMPETITIONS
@@ -82,7 +155,7 @@ type Competition struct {
 // out of date meaning the latest competition is not listed.  In this case
 // we need to attempt to re-read from the web and try again.  If the key is
 // still not found this implies an error in oom.conf (e.g. a non-existant
-// competition has been asked for)
+// competition has been asked for), and an error is returned
 
 // return first key in slice that is not in the map
 func firstMissingKey(c []Competition, m map[string]Competition) (bool, string) {
@@ -100,37 +173,50 @@ func firstMissingKey(c []Competition, m map[string]Competition) (bool, string) {
 }
 
 // 8-jan-2020: modify to read all comps from website for 2018..year
-func FetchCompDescriptions(year int, fname string) []Competition {
-  oomCompetitions := parseKeysFromFile(fname) // may also set URL, is a slice
+func (c *Client) FetchCompDescriptions(year int, fname string) ([]Competition, error) {
+  oomCompetitions, err := parseKeysFromFile(fname) // may also set URL, is a slice
+  if err != nil {
+    return nil, err
+  }
   // ignore year for now - 2018..2020 at present
 	var startYear = 2018
 	var allCompetitions = make(map[string]Competition)
 	var d []byte
 	var fromCache bool
 	for startYear <= year {
-		d, fromCache = fetchAllCompsPage(startYear, true) // noting cache may be stale
+		d, fromCache, err = c.fetchAllCompsPage(startYear, true) // noting cache may be stale
+		if err != nil {
+			return nil, err
+		}
 	  yearCompetitions := parseWebComps(string(d)) // may be stale, is a map
 		for k, v := range yearCompetitions {
 			allCompetitions[k] = v
 		}
 		startYear += 1
 	}
-	log.Println("merged comp lists from 2018 ....")
+	Logger.Debug("merged comp lists", "from", 2018, "to", year)
 
 	// check all the comp keys from the file are found in the web page
 	missing, missingKey := firstMissingKey(oomCompetitions, allCompetitions)
-	if missing && !fromCache {
-		log.Fatal("Competition id %s not found on web site list of comps",
+	if missing && c.Offline {
+		// can't refetch, the competition's name is taken from its cached
+		// results if any
+		Logger.Warn("competition not found in cached lists of comps", "key", missingKey)
+	} else if missing && !fromCache {
+		return nil, fmt.Errorf("Competition id %s not found on web site list of comps",
 			missingKey)
 	} else {
 		if missing && fromCache {
 			// read from web and try again - should read all years...
-			d, fromCache = fetchAllCompsPage(year, false)
+			d, fromCache, err = c.fetchAllCompsPage(year, false)
+			if err != nil {
+				return nil, err
+			}
 			allCompetitions = parseWebComps(string(d))
 			missing, missingKey := firstMissingKey(oomCompetitions, allCompetitions)
 			if missing && !fromCache {
-				log.Fatal("Competition id ", missingKey,
-					" not found on web site list of comps")
+				return nil, fmt.Errorf("Competition id %s not found on web site list of comps",
+					missingKey)
 			}
 		}
 	}
@@ -149,48 +235,137 @@ func FetchCompDescriptions(year int, fname string) []Competition {
       }
     }
   }
-  return oomCompetitions
+  rules, err := parseRulesFromFile(fname)
+  if err != nil {
+    return nil, err
+  }
+  return append(oomCompetitions, matchRules(rules, allCompetitions, year, oomCompetitions)...), nil
+}
+
+// matchRule selects competitions by name, see above
+type matchRule struct {
+	re    *regexp.Regexp
+	major bool
+}
+
+// parseRulesFromFile reads the "match" lines in the file
+func parseRulesFromFile(fname string) ([]matchRule, error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	var ret []matchRule
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) < 2 || strings.TrimSpace(fields[0]) != "match" {
+			continue
+		}
+		re, err := regexp.Compile("(?i)" + strings.TrimSpace(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fname, err)
+		}
+		rule := matchRule{re: re}
+		for _, field := range fields[2:] {
+			if strings.TrimSpace(field) == "major" {
+				rule.major = true
+			}
+		}
+		ret = append(ret, rule)
+	}
+	return ret, nil
+}
+
+// matchRules returns the competitions in the year matching the rules, in
+// date order, leaving out those already listed
+func matchRules(rules []matchRule, all map[string]Competition, year int, listed []Competition) []Competition {
+	seen := make(map[string]bool)
+	for _, comp := range listed {
+		seen[comp.Key] = true
+	}
+	var ret []Competition
+	for _, comp := range all {
+		if seen[comp.Key] || comp.Time().Year() != year {
+			continue
+		}
+		for _, rule := range rules {
+			if rule.re.MatchString(comp.Name) {
+				comp.Label = comp.Name
+				comp.URL += "&sort=1" // as for those listed by key
+				comp.Major = comp.Major || rule.major
+				ret = append(ret, comp)
+				break
+			}
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if !ret[i].Time().Equal(ret[j].Time()) {
+			return ret[i].Time().Before(ret[j].Time())
+		}
+		return ret[i].Key < ret[j].Key
+	})
+	return ret
+}
+
+// allCompsURL returns the URL of the page listing the year's competitions
+func allCompsURL(year int) string {
+	return fmt.Sprintf("http://www.colchestergolfclub.com/competition.php?showall=1&time=&show=&year=%d", year)
 }
 
-func fetchAllCompsPage(year int, useCached bool) (d []byte, fromCache bool) {
-	fname := fmt.Sprintf("all_comps_%d.dat", year)
+// fetchAllCompsPage returns the page listing the year's competitions, from
+// the cache if useCached and it's there, otherwise from the website,
+// caching it.  Offline it returns no page rather than fetching it
+func (c *Client) fetchAllCompsPage(year int, useCached bool) (d []byte, fromCache bool, err error) {
+	fname := c.cachePath(fmt.Sprintf("all_comps_%d.dat", year))
 	if useCached {
 		d1, err := ioutil.ReadFile(fname)
 		if err == nil {
 			d = d1 // this is where := is a bit crappy
-			fromCache = true
-			return
+			return d, true, nil
 		}
 	}
-  url := fmt.Sprintf("http://www.colchestergolfclub.com/competition.php?showall=1&time=&show=&year=%d", year)
-  d = MustFetch(url)
-  ioutil.WriteFile(fname, d, 0644)
+	if c.Offline {
+		return
+	}
+  d, err = c.fetch(context.Background(), allCompsURL(year))
+  if err != nil {
+    return nil, false, err
+  }
+  err = ioutil.WriteFile(fname, d, 0644)
 	return
 }
 
 // fetchAllCompDesc returns a []Competition with the first descriptive set of
 //  fields filled in.  All competitions from the given year are populated
 // TODO use cached all_comps.dat
-func FetchAllCompDesc(year int) []Competition {
-  log.Println("building competition descriptions...")
-	d, _ := fetchAllCompsPage(year, true)
+func (c *Client) FetchAllCompDesc(year int) ([]Competition, error) {
+  Logger.Debug("building competition descriptions", "year", year)
+	d, _, err := c.fetchAllCompsPage(year, true)
+	if err != nil {
+		return nil, err
+	}
 	cMap := parseWebComps(string(d))
 	var cSlice []Competition
 	for _, v := range cMap {
 		cSlice = append(cSlice, v)
 	}
-	return cSlice // can't win as want map some places and slices in others
+	return cSlice, nil // can't win as want map some places and slices in others
 }
 
 
+// CompKeys returns the competitions listed in fname, see
+// parseKeysFromFile, without fetching their descriptions
+func CompKeys(fname string) ([]Competition, error) {
+  return parseKeysFromFile(fname)
+}
+
 // parseKeysFromFile reads the file and populates the Key field,
 // returning a []Competition.
 // If the URL read from file appears valid (a whole URL, not just the
 // key fragment), it is populated in URL
-func parseKeysFromFile(fname string) []Competition {
+func parseKeysFromFile(fname string) ([]Competition, error) {
   file, err := os.Open(fname)
   if err != nil {
-    log.Fatal(err)
+    return nil, err
   }
   defer file.Close()
 
@@ -199,7 +374,8 @@ func parseKeysFromFile(fname string) []Competition {
   for scanner.Scan() {
     compid, _ := parseNextCompKey(scanner.Text(), 0)
     if compid != "" {
-      desc := Competition{Key: compid}
+      desc := Competition{Key: compid,
+        Label: strings.TrimSpace(strings.Split(scanner.Text(), ",")[0])}
       // now test if the ?compid= is part of a valid url, and if so
       // put the url in the desc
       s := strings.TrimSpace(strings.Split(scanner.Text(), ",")[1])
@@ -209,13 +385,18 @@ func parseKeysFromFile(fname string) []Competition {
           desc.URL = s
         }
       }
+      for _, field := range strings.Split(scanner.Text(), ",")[2:] {
+        if strings.TrimSpace(field) == "major" {
+          desc.Major = true
+        }
+      }
       ret = append(ret, desc)
     }
   }
 	if err := scanner.Err(); err != nil {
-    log.Fatal(err)
+    return nil, err
   }
-  return ret
+  return ret, nil
 }
 
 // used with parseNextCompid
@@ -284,84 +465,167 @@ func tokenEnd(s string, start int, terminator string) int {
 // Load populates the comptition identifed by the comp.Key.
 // A valid comp.URL is required unless the results are already cached
 // The competition is read from the cached file 'key.txt' if present.
-// Otherwise the web page is fetched, parsed, and the cached file created.
+// Otherwise the web page is fetched, parsed, and the cached file created,
+// unless Offline when ErrNotCached is returned.
 // The optional urlString is used if supplied, otherwise a default
 // url is constructed based on the key
-func Load(comp *Competition) {
+func (c *Client) Load(comp *Competition) error {
+	return c.LoadContext(context.Background(), comp)
+}
+
+// LoadContext is Load, abandoning the fetch of the web page if ctx is
+// cancelled
+func (c *Client) LoadContext(ctx context.Context, comp *Competition) error {
 	if comp.Key == "" {
-		err := errors.New("competition.Load: Invalid null competetiton key supplied")
-		log.Fatal(err)
+		return errors.New("competition.Load: Invalid null competetiton key supplied")
+	}
+	if ok, err := c.readCached(comp); ok || err != nil { return err }
+	if c.Offline { return ErrNotCached }
+	if err := c.populateResultsFromWeb(ctx, comp); err != nil {
+		return err
 	}
-	if readCached(comp) { return }
-	populateResultsFromWeb(comp)
-	saveComp(comp)
+	return c.saveComp(comp)
 }
 
+// LoadCached populates the competition identified by comp.Key from the
+// cached file only, returning false if it is not cached, or an error if
+// the cached file is malformed
+func (c *Client) LoadCached(comp *Competition) (bool, error) {
+	return c.readCached(comp)
+}
+
+// overrideRE matches a "# override: NAME" line in a cached file
+var overrideRE = regexp.MustCompile(`^#\s*override:\s*(.+)$`)
 
 // readCached returns false if there is no cached file, otherwise the
-// Competition is returned along with true
-func readCached(comp *Competition) bool {
-	fname := comp.Key + ".txt"
+// Competition is returned along with true, or an error if the file isn't
+// one saveComp wrote, say a note saved under a numeric name
+func (c *Client) readCached(comp *Competition) (bool, error) {
+	fname := c.cachePath(comp.Key + ".txt")
 	f, err := os.Open(fname)
 	if err != nil {
-		return false
+		return false, nil
 	}
 	defer f.Close()
 	scanner := bufio.NewScanner(f)
-	_ = scanner.Scan()
-	for scanner.Text()[0:1] == "#" {
-		_ = scanner.Scan()
-	}
-	comp.Key = strings.TrimSpace(strings.Split(scanner.Text(), ",")[1])
-	_ = scanner.Scan()
-	comp.Name = strings.TrimSpace(strings.Split(scanner.Text(), ",")[1])
-	_ = scanner.Scan()
-	comp.Date = strings.TrimSpace(strings.Split(scanner.Text(), ",")[1])
-	_ = scanner.Scan()
-	comp.URL = strings.TrimSpace(strings.Split(scanner.Text(), ",")[1])
-	_ = scanner.Scan()
-	comp.NumPlayers, _ = strconv.Atoi(strings.TrimSpace(strings.Split(scanner.Text(), ",")[1]))
-	_ = scanner.Scan()
+	line := 0
+	next := func() bool {
+		line++
+		return scanner.Scan()
+	}
+	malformed := func(what string) (bool, error) {
+		if err := scanner.Err(); err != nil {
+			return false, fmt.Errorf("%s: %v", fname, err)
+		}
+		return false, fmt.Errorf("%s:%d: expected %s", fname, line, what)
+	}
+	if !next() {
+		return malformed("key")
+	}
+	comp.Overrides, comp.notes = nil, nil
+	for strings.HasPrefix(scanner.Text(), "#") {
+		if m := overrideRE.FindStringSubmatch(scanner.Text()); m != nil {
+			comp.Overrides = append(comp.Overrides, strings.TrimSpace(m[1]))
+		} else {
+			comp.notes = append(comp.notes, scanner.Text())
+		}
+		if !next() {
+			return malformed("key")
+		}
+	}
+	var values [5]string // key, name, date, url, number of players
+	for i, field := range []string{"key", "name", "date", "url", "number of players"} {
+		s := strings.SplitN(scanner.Text(), ",", 2)
+		if len(s) != 2 {
+			return malformed(field)
+		}
+		values[i] = strings.TrimSpace(s[1])
+		if !next() {
+			return malformed("the header row")
+		}
+	}
+	comp.Key, comp.Name, comp.Date, comp.URL = values[0], values[1], values[2], values[3]
+	comp.NumPlayers, _ = strconv.Atoi(values[4])
 	// ignore the header row
 	comp.Results = make(map[string]PlayerResult)
-	for scanner.Scan() {
+	for next() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
 		s := strings.Split(scanner.Text(), ",")
+		if len(s) < 4 {
+			return malformed("points, rank, result and name")
+		}
 		var playerResult PlayerResult
 		playerResult.Name = strings.TrimSpace(s[3])
+		if len(s) > 4 { // id column added after the first caches were saved
+			playerResult.ID = strings.TrimSpace(s[4])
+		}
 		playerResult.Result = strings.TrimSpace(s[2])
 		playerResult.Rank, _ = strconv.Atoi(strings.TrimSpace(strings.Split(scanner.Text(), ",")[1]))
 		playerResult.OOMPoints, _ = strconv.Atoi(strings.TrimSpace(strings.Split(scanner.Text(), ",")[0]))
 		comp.Results[playerResult.Name] = playerResult
 	}
 	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
+		return false, fmt.Errorf("%s: %v", fname, err)
 	}
-	return true
+	return true, nil
 }
 
 // saveComp creates a cache file for the competition that can be read back in
-func saveComp(comp *Competition) {
-  fname:= fmt.Sprintf("%s.txt", comp.Key)
-  f, err := os.Create(fname)
-  if err != nil {log.Fatal(err)}
-  defer f.Close()
+func (c *Client) saveComp(comp *Competition) error {
+  fname:= c.cachePath(fmt.Sprintf("%s.txt", comp.Key))
+  f := new(bytes.Buffer)
   eol := "\r\n"
+  for _, note := range comp.notes {
+    fmt.Fprint(f, note, eol)
+  }
+  for _, name := range comp.Overrides {
+    fmt.Fprint(f, "# override: ", name, eol)
+  }
   fmt.Fprint(f, "key, ", comp.Key, eol)
   fmt.Fprint(f, "name, ", comp.Name, eol)
   fmt.Fprint(f, "date, ", comp.Date, eol)
   fmt.Fprint(f, "url, ", comp.URL, eol)
   fmt.Fprint(f, "number of players, ", comp.NumPlayers, eol)
-  fmt.Fprint(f, "oom_points, rank_in_comp, igresult, name - row per player", eol)
+  fmt.Fprint(f, "oom_points, rank_in_comp, igresult, name, playerid - row per player", eol)
   for _,p := range comp.Results {
-    s := fmt.Sprintf("%10v, %12v, %8v, %v, %s", p.OOMPoints, p.Rank, p.Result, p.Name, eol)
+    s := fmt.Sprintf("%10v, %12v, %8v, %v, %v%s", p.OOMPoints, p.Rank, p.Result, p.Name, p.ID, eol)
     fmt.Fprint(f, s)
   }
+  return writeFileAtomic(fname, f.Bytes(), 0644)
+}
+
+// writeFileAtomic writes data to a temporary file beside fname and renames
+// it into place, so fname is never left half written
+func writeFileAtomic(fname string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(fname), filepath.Base(fname)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), fname)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
 }
 
 // populateResultsFromWeb gets the page pointed by Competition.URL, and parses the
 // results in to the passed Competition
-func populateResultsFromWeb(comp *Competition) {
-	data := MustFetch(comp.URL)
+func (c *Client) populateResultsFromWeb(ctx context.Context, comp *Competition) error {
+	data, err := c.fetch(ctx, comp.URL)
+	if err != nil {
+		return err
+	}
   // Have seen two formats for web page
   // 1. use of ?playerid= used for most competitions
   // 2. use of class="namecol" for the club championships with two rounds
@@ -381,16 +645,20 @@ func populateResultsFromWeb(comp *Competition) {
 		if first {
       first = false // scan and discard page up to start of first player result
     } else {
-      name, handicap, result := detail(scanner.Text())
+      name, id, handicap, result, err := detail(scanner.Text())
+      if err != nil {
+        return fmt.Errorf("%s: %v", comp.URL, err)
+      }
       if(handicap <= 36) {
         numPlayers++
         var player PlayerResult
         player.Name = name
+        player.ID = id
         player.Result = result
         player.Rank = numPlayers
         res = append(res, player)
       } else {
-        fmt.Println("omitted player as handicap over 36")
+        Logger.Info("omitted player as handicap over 36", "comp", comp.Key, "player", name, "handicap", handicap)
       }
     }
 	}
@@ -409,6 +677,7 @@ func populateResultsFromWeb(comp *Competition) {
 		}
     comp.Results[p.Name] = p
   }
+  return nil
 }
 
 func compSplitFunc(data []byte, atEOF bool) (advance int, token []byte, err error) {
@@ -449,7 +718,11 @@ func champSplitFunc(data []byte, atEOF bool) (advance int, token []byte, err err
 //<td><a href="viewround.php?roundid=16413" title="Countback results: Back 9 - 12, Back 6 - 8, Back 3 - 4, Back 1 - 2">24</a></td>
 //<td></td>
 //</tr>
-func playerDetail(s string) (name string, handicap_int int, score string) {
+func playerDetail(s string) (name string, id string, handicap_int int, score string, err error) {
+    // token starts after the ? of ?playerid=
+    if strings.HasPrefix(s, "playerid=") {
+      id = s[len("playerid="):endInt(s, len("playerid="))]
+    }
     start := strings.Index(s, ">")
     end := strings.Index(s, "</a>")
     name = s[start + 1:end]
@@ -470,7 +743,7 @@ func playerDetail(s string) (name string, handicap_int int, score string) {
     return
 }
 
-func champDetail(s string) (name string, handicap_int int, score string) {
+func champDetail(s string) (name string, id string, handicap_int int, score string, err error) {
   handicap_int = 0 // needs fixed!
   //fmt.Println("champDetails: ", s)
   start := strings.Index(s, ">")
@@ -484,13 +757,10 @@ func champDetail(s string) (name string, handicap_int int, score string) {
     s = s[end:]
     end = strings.Index(s, "</td></tr>")
     if end == -1 {
-      fmt.Println(s)
-      os.Exit(1)
-    }
-    s = s[:end]
-    if "</span>" == s[len(s)-7:] {
-      s = s[:len(s) - 7]
+      err = fmt.Errorf("can't parse championship result %q", s)
+      return
     }
+    s = strings.TrimSuffix(s[:end], "</span>")
     start = strings.LastIndex(s, ">")
     score = s[start + 1:]
     if "&nbsp;" == score {
@@ -2,10 +2,93 @@ package oom
 
 import (
 	//"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
-	Load(&Competition{Key: "1266"})
-	// if it gets here that is success!
+	if err := NewClient().Load(&Competition{Key: "1266"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadOffline(t *testing.T) {
+	c := NewClient()
+	c.Offline = true
+	comp := Competition{Key: "1266"}
+	if err := c.Load(&comp); err != nil || comp.NumPlayers == 0 {
+		t.Errorf("expected 1266 loaded from the cache, got %v", err)
+	}
+	comp = Competition{Key: "999999", Label: "elmstead"}
+	if err := c.Load(&comp); err != ErrNotCached {
+		t.Errorf("expected ErrNotCached, got %v", err)
+	}
+	if comp.Title() != "elmstead (999999)" {
+		t.Errorf("expected elmstead (999999), got %s", comp.Title())
+	}
+}
+
+func TestCompetitionTime(t *testing.T) {
+	c := Competition{Date: "Fri 25th Mar '16"}
+	if want := time.Date(2016, 3, 25, 0, 0, 0, 0, time.UTC); !c.Time().Equal(want) {
+		t.Errorf("expected %v, got %v", want, c.Time())
+	}
+	if c := (Competition{Date: "tbc"}); !c.Time().IsZero() {
+		t.Errorf("expected zero time, got %v", c.Time())
+	}
+}
+
+func TestPlayerDetail(t *testing.T) {
+	name, id, handicap, score, _ := playerDetail(`playerid=76041">Jo Mager</a>(16)</td>
+<td><a href="viewround.php?roundid=16413" title="Countback">24</a></td>`)
+	if name != "Jo Mager" || id != "76041" || handicap != 16 || score != "24" {
+		t.Errorf("expected Jo Mager 76041 16 24, got %s %s %d %s", name, id, handicap, score)
+	}
+}
+
+func TestChampDetail(t *testing.T) {
+	name, _, _, score, err := champDetail(`lass="namecol">Jo Mager (16)</td><td>80</td><td><span>158</span></td></tr>`)
+	if err != nil || name != "Jo Mager" || score != "158" {
+		t.Errorf("expected Jo Mager 158, got %s %s %v", name, score, err)
+	}
+	if _, _, _, _, err := champDetail(`lass="namecol">Jo Mager (16)</td><td>80`); err == nil {
+		t.Error("expected an error for a truncated result")
+	}
+}
+
+func TestMatchRules(t *testing.T) {
+	f, err := ioutil.TempFile("", "oomconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("elmstead, ?compid=1266\nmatch, medal$, major\nmatch, ^stableford\n")
+	f.Close()
+	rules, err := parseRulesFromFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := CompKeys(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || !rules[0].major || rules[1].major {
+		t.Fatalf("expected a major and a minor rule, got %v", rules)
+	}
+	all := map[string]Competition{
+		"1266": {Key: "1266", Name: "Elmstead Medal", Date: "Fri 25th Mar '16"},
+		"1300": {Key: "1300", Name: "Spring Medal", Date: "Sat 9th Apr '16"},
+		"1290": {Key: "1290", Name: "Stableford Cup", Date: "Sat 2nd Apr '16"},
+		"1310": {Key: "1310", Name: "Medal Final", Date: "Sat 16th Apr '16"},
+		"900":  {Key: "900", Name: "Autumn Medal", Date: "Sat 3rd Oct '15"},
+	}
+	got := matchRules(rules, all, 2016, keys)
+	if len(got) != 2 || got[0].Key != "1290" || got[1].Key != "1300" {
+		t.Fatalf("expected 1290 and 1300, got %v", got)
+	}
+	if got[0].Major || !got[1].Major || got[1].Label != "Spring Medal" {
+		t.Errorf("expected only the Spring Medal major, got %v", got)
+	}
 }
@@ -0,0 +1,135 @@
+package oom
+
+// counting.go decides which of a player's scores count towards their
+// order of merit total.  The rules, all taken from Options, are applied
+// to the scores best first:
+// - at most MaxComps scores count over the season
+// - at most PerPeriod scores count in each Period (month, quarter, half)
+// - the best MinMajors scores from competitions marked major in oom.conf
+//   always count, ahead of better scores from other competitions
+// Scores that don't count are dropped, and shown in brackets in the output
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Period divides the season for the PerPeriod counting rule
+type Period int
+
+// The available periods
+const (
+	NoPeriod Period = iota
+	Month
+	Quarter
+	Half
+)
+
+var periodNames = map[Period]string{
+	NoPeriod: "none",
+	Month:    "month",
+	Quarter:  "quarter",
+	Half:     "half",
+}
+
+func (p Period) String() string {
+	return periodNames[p]
+}
+
+// MarshalText writes the period by name in the json output
+func (p Period) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText reads a period name
+func (p *Period) UnmarshalText(text []byte) error {
+	period, err := ParsePeriod(string(text))
+	*p = period
+	return err
+}
+
+// ParsePeriod converts "none", "month", "quarter" or "half" to a Period
+func ParsePeriod(s string) (Period, error) {
+	for p, name := range periodNames {
+		if strings.EqualFold(strings.TrimSpace(s), name) {
+			return p, nil
+		}
+	}
+	return NoPeriod, errors.New("oom: unknown period " + s)
+}
+
+// index returns a number identifying the period containing t
+func (p Period) index(t time.Time) int {
+	switch p {
+	case Month:
+		return t.Year()*12 + int(t.Month()) - 1
+	case Quarter:
+		return t.Year()*4 + (int(t.Month())-1)/3
+	case Half:
+		return t.Year()*2 + (int(t.Month())-1)/6
+	}
+	return 0
+}
+
+// countEntry is one of a player's scores as seen by the counting rules
+type countEntry struct {
+	key    string
+	points int
+	date   time.Time
+	major  bool
+}
+
+// selectCounted returns the keys of the competitions whose scores count
+// for the player
+func (s *Standings) selectCounted(p PlayerOOM) map[string]bool {
+	var entries []countEntry
+	for _, comp := range s.Competitions {
+		if r, ok := p.PlayerByComp[comp.Key]; ok {
+			entries = append(entries, countEntry{key: comp.Key,
+				points: r.OOMPoints, date: comp.Time(), major: comp.Major})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].points != entries[j].points {
+			return entries[i].points > entries[j].points
+		}
+		return entries[i].date.Before(entries[j].date) // earlier score counts
+	})
+
+	opts := s.Options
+	counted := make(map[string]bool)
+	inPeriod := make(map[int]int)
+	canCount := func(e countEntry) bool {
+		if counted[e.key] {
+			return false
+		}
+		if opts.MaxComps > 0 && len(counted) >= opts.MaxComps {
+			return false
+		}
+		if opts.Period != NoPeriod && opts.PerPeriod > 0 &&
+			inPeriod[opts.Period.index(e.date)] >= opts.PerPeriod {
+			return false
+		}
+		return true
+	}
+	count := func(e countEntry) {
+		counted[e.key] = true
+		inPeriod[opts.Period.index(e.date)]++
+	}
+
+	majors := 0
+	for _, e := range entries {
+		if e.major && majors < opts.MinMajors && canCount(e) {
+			count(e)
+			majors++
+		}
+	}
+	for _, e := range entries {
+		if canCount(e) {
+			count(e)
+		}
+	}
+	return counted
+}
@@ -0,0 +1,52 @@
+package oom
+
+import (
+	"testing"
+)
+
+func TestSelectCounted(t *testing.T) {
+	// Ann wins all four, scoring 5, 4, 3 then 2 points
+	comps := []Competition{
+		testComp("1", "Sat 2nd Jan '16", "Ann", "Bea", "Cat", "Dot", "Eve"),
+		testComp("2", "Sat 9th Jan '16", "Ann", "Bea", "Cat", "Dot"),
+		testComp("3", "Sat 2nd Apr '16", "Ann", "Bea", "Cat"),
+		testComp("4", "Sat 9th Apr '16", "Ann", "Bea"),
+	}
+	comps[3].Major = true
+	tests := []struct {
+		opts Options
+		want string // counted keys in competition order
+	}{
+		{Options{}, "1234"},
+		{Options{MaxComps: 2}, "12"},
+		{Options{Period: Quarter, PerPeriod: 1}, "13"},
+		{Options{MaxComps: 2, MinMajors: 1}, "14"},
+		{Options{MaxComps: 3, Period: Month, PerPeriod: 1, MinMajors: 1}, "14"},
+	}
+	for _, test := range tests {
+		s := NewStandings(2016, comps, test.opts)
+		ann := s.OOMResults["Ann"]
+		got, total := "", 0
+		for _, comp := range comps {
+			if r := ann.PlayerByComp[comp.Key]; r.Counted {
+				got += comp.Key
+				total += r.OOMPoints
+			}
+		}
+		if got != test.want || total != ann.OOMPoints {
+			t.Errorf("%+v: expected %s counted, got %s with total %d/%d",
+				test.opts, test.want, got, total, ann.OOMPoints)
+		}
+	}
+}
+
+func TestFormatDropped(t *testing.T) {
+	p := PlayerResult{OOMPoints: 9, Rank: 20, Result: "31"}
+	if got := formatPlayerResult(p, true); got != "[9] (20th 31)" {
+		t.Errorf("expected dropped score in brackets, got %s", got)
+	}
+	p.Counted = true
+	if got := formatPlayerResult(p, false); got != "9" {
+		t.Errorf("expected 9, got %s", got)
+	}
+}
@@ -0,0 +1,263 @@
+package oom
+
+// creds.go finds the email and pin for a Client to log in to the website
+// with, trying in turn:
+// - environment variables OOM_EMAIL and OOM_PIN
+// - the client's NetrcFile, a netrc file with a "machine" entry for the website, which
+//   only its owner may read
+// - EncryptedCredsFile, written by SaveEncryptedCreds and unlocked by a
+//   passphrase from OOM_PASSPHRASE or the terminal
+// - CredsFile, the email and pin on two lines in plain text
+// - the terminal, the pin not being echoed
+// The pin is never logged
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+// credsMagic starts an encrypted credentials file, followed by the salt,
+// the nonce and the sealed "email\npin"
+const credsMagic = "oom-creds/1\n"
+
+// pbkdf2Iterations derive the key from the passphrase, slowing guessing,
+// a variable for the tests
+var pbkdf2Iterations = 600000
+
+// stdin is shared by the prompts, so that piped input isn't lost to a
+// scanner's buffer
+var stdin = bufio.NewReader(os.Stdin)
+
+// credentials returns the email and pin to log in with, and where they
+// came from
+func (c *Client) credentials() (email string, pin string, source string, err error) {
+	if email, pin = os.Getenv("OOM_EMAIL"), os.Getenv("OOM_PIN"); email != "" && pin != "" {
+		return email, pin, "environment", nil
+	}
+	u, err := url.Parse(c.loginURL)
+	if err != nil {
+		return "", "", "", err
+	}
+	if c.NetrcFile != "" {
+		if email, pin, err = readNetrc(c.NetrcFile, u.Hostname()); err != nil || email != "" {
+			return email, pin, c.NetrcFile, err
+		}
+	}
+	if _, err := os.Stat(c.EncryptedCredsFile); err == nil {
+		passphrase := os.Getenv("OOM_PASSPHRASE")
+		if passphrase == "" {
+			if passphrase, err = ReadSecret("Passphrase for " + c.EncryptedCredsFile + ": "); err != nil {
+				return "", "", "", err
+			}
+		}
+		email, pin, err = ReadEncryptedCreds(c.EncryptedCredsFile, passphrase)
+		return email, pin, c.EncryptedCredsFile, err
+	}
+	if data, err := ioutil.ReadFile(c.CredsFile); err == nil {
+		if err := checkPrivate(c.CredsFile); err != nil {
+			Logger.Warn("plain text credentials", "err", err)
+		}
+		lines := strings.SplitN(string(data), "\n", 3)
+		if len(lines) < 2 {
+			return "", "", "", fmt.Errorf("%s: expected email and pin on two lines", c.CredsFile)
+		}
+		return strings.TrimSpace(lines[0]), strings.TrimSpace(lines[1]), c.CredsFile, nil
+	}
+	email, pin, err = readCredsStdin()
+	return email, pin, "terminal", err
+}
+
+// checkPrivate returns an error if anyone but the owner may read or write
+// fname
+func checkPrivate(fname string) error {
+	fi, err := os.Stat(fname)
+	if err != nil {
+		return err
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%s is accessible by others (mode %v), chmod 600 it", fname, fi.Mode().Perm())
+	}
+	return nil
+}
+
+// readNetrc returns the login and password of machine in the netrc file
+// fname, or empty strings if there is no such file or machine.  The file
+// must be private, as ftp and curl insist
+func readNetrc(fname string, machine string) (login string, password string, err error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return "", "", nil
+	}
+	var fields []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		fields = append(fields, strings.Fields(line)...)
+	}
+	in := false // the machine's entry, or the default entry
+entries:
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine", "default":
+			if in {
+				break entries
+			}
+			if fields[i] == "default" {
+				in = true
+			} else if i++; i < len(fields) {
+				in = fields[i] == machine
+			}
+		case "login", "password", "account":
+			if i++; in && i < len(fields) {
+				if fields[i-1] == "login" {
+					login = fields[i]
+				} else if fields[i-1] == "password" {
+					password = fields[i]
+				}
+			}
+		}
+	}
+	if login == "" || password == "" {
+		return "", "", nil
+	}
+	if err := checkPrivate(fname); err != nil {
+		return "", "", err
+	}
+	return login, password, nil
+}
+
+// SaveEncryptedCreds writes the email and pin to fname, readable by the
+// owner only, encrypted with AES-GCM using a key derived from passphrase
+func SaveEncryptedCreds(fname string, passphrase string, email string, pin string) error {
+	if passphrase == "" {
+		return errors.New("oom: empty passphrase")
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	gcm, err := credsCipher(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	b.WriteString(credsMagic)
+	b.Write(salt)
+	b.Write(nonce)
+	b.Write(gcm.Seal(nil, nonce, []byte(email+"\n"+pin), []byte(credsMagic)))
+	return ioutil.WriteFile(fname, b.Bytes(), 0600)
+}
+
+// ReadEncryptedCreds returns the email and pin saved in fname by
+// SaveEncryptedCreds
+func ReadEncryptedCreds(fname string, passphrase string) (email string, pin string, err error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return "", "", err
+	}
+	if !bytes.HasPrefix(data, []byte(credsMagic)) || len(data) < len(credsMagic)+16 {
+		return "", "", fmt.Errorf("%s is not an encrypted credentials file", fname)
+	}
+	data = data[len(credsMagic):]
+	gcm, err := credsCipher(passphrase, data[:16])
+	if err != nil {
+		return "", "", err
+	}
+	data = data[16:]
+	if len(data) < gcm.NonceSize() {
+		return "", "", fmt.Errorf("%s is truncated", fname)
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(credsMagic))
+	if err != nil {
+		return "", "", fmt.Errorf("%s: wrong passphrase or corrupt file", fname)
+	}
+	lines := strings.SplitN(string(plain), "\n", 2)
+	if len(lines) != 2 {
+		return "", "", fmt.Errorf("%s: expected email and pin", fname)
+	}
+	return lines[0], lines[1], nil
+}
+
+func credsCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ReadLine prompts for and reads a line from stdin
+func ReadLine(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// ReadSecret prompts for and reads a line from stdin without echoing it,
+// when stdin is a terminal, refusing to prompt if the echo can't be turned
+// off.  The echo is turned back on if interrupted while reading
+func ReadSecret(prompt string) (string, error) {
+	fi, err := os.Stdin.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return ReadLine(prompt) // piped, nothing to echo
+	}
+	restore, err := noEcho(os.Stdin.Fd())
+	if err != nil {
+		return "", fmt.Errorf("can't turn off the echo to prompt for a secret (%v), "+
+			"set $OOM_EMAIL and $OOM_PIN, use a netrc file, or save them with oom creds "+
+			"and set $OOM_PASSPHRASE", err)
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-interrupt:
+			restore()
+			fmt.Fprintln(os.Stderr)
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+	defer func() {
+		signal.Stop(interrupt)
+		close(done)
+		restore()
+		fmt.Fprintln(os.Stderr)
+	}()
+	return ReadLine(prompt)
+}
+
+func readCredsStdin() (email string, pin string, err error) {
+	if email, err = ReadLine("Enter email: "); err != nil {
+		return "", "", err
+	}
+	if pin, err = ReadSecret("Enter PIN: "); err != nil {
+		return "", "", err
+	}
+	return email, pin, nil
+}
@@ -0,0 +1,98 @@
+package oom
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadNetrc(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "netrc")
+	ioutil.WriteFile(fname, []byte(`# other sites
+machine example.com login bob password secret
+machine www.colchestergolfclub.com
+	login jo@example.com
+	password 1234
+default login anon password guest
+`), 0600)
+	tests := []struct{ machine, login, password string }{
+		{"www.colchestergolfclub.com", "jo@example.com", "1234"},
+		{"example.com", "bob", "secret"},
+		{"other.com", "anon", "guest"},
+	}
+	for _, test := range tests {
+		login, password, err := readNetrc(fname, test.machine)
+		if err != nil || login != test.login || password != test.password {
+			t.Errorf("%s: expected %s %s, got %s %s %v", test.machine, test.login, test.password,
+				login, password, err)
+		}
+	}
+	if login, _, err := readNetrc(filepath.Join(dir, "missing"), "example.com"); err != nil || login != "" {
+		t.Errorf("missing file: expected no login, got %q %v", login, err)
+	}
+	os.Chmod(fname, 0644)
+	if _, _, err := readNetrc(fname, "example.com"); err == nil {
+		t.Errorf("readable by others: expected an error")
+	}
+}
+
+func TestEncryptedCreds(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(n int) { pbkdf2Iterations = n }(pbkdf2Iterations)
+	pbkdf2Iterations = 1000
+	fname := filepath.Join(dir, "creds.enc")
+	if err := SaveEncryptedCreds(fname, "open sesame", "jo@example.com", "1234"); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v", fi.Mode().Perm())
+	}
+	email, pin, err := ReadEncryptedCreds(fname, "open sesame")
+	if err != nil || email != "jo@example.com" || pin != "1234" {
+		t.Errorf("expected jo@example.com 1234, got %s %s %v", email, pin, err)
+	}
+	if _, _, err := ReadEncryptedCreds(fname, "open barley"); err == nil {
+		t.Errorf("wrong passphrase: expected an error")
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	defer func(n int) { pbkdf2Iterations = n }(pbkdf2Iterations)
+	pbkdf2Iterations = 1000
+	c := newTestClient(t)
+	c.NetrcFile = filepath.Join(c.CacheDir, "netrc")
+	t.Setenv("OOM_EMAIL", "")
+	t.Setenv("OOM_PIN", "")
+	t.Setenv("OOM_PASSPHRASE", "open sesame")
+
+	check := func(what, wantEmail, wantPin, wantSource string) {
+		email, pin, source, err := c.credentials()
+		if err != nil || email != wantEmail || pin != wantPin || source != wantSource {
+			t.Errorf("%s: expected %s %s from %s, got %s %s from %s %v", what,
+				wantEmail, wantPin, wantSource, email, pin, source, err)
+		}
+	}
+	ioutil.WriteFile(c.CredsFile, []byte("plain@example.com\n1111\n"), 0600)
+	check("plain", "plain@example.com", "1111", c.CredsFile)
+	SaveEncryptedCreds(c.EncryptedCredsFile, "open sesame", "enc@example.com", "2222")
+	check("encrypted", "enc@example.com", "2222", c.EncryptedCredsFile)
+	ioutil.WriteFile(c.NetrcFile, []byte("machine www.colchestergolfclub.com login netrc@example.com password 3333\n"), 0600)
+	check("netrc", "netrc@example.com", "3333", c.NetrcFile)
+	t.Setenv("OOM_EMAIL", "env@example.com")
+	t.Setenv("OOM_PIN", "4444")
+	check("environment", "env@example.com", "4444", "environment")
+}
@@ -0,0 +1,252 @@
+package oom
+
+// csv.go writes the standings as RFC 4180 csv, so a competition name
+// containing a comma or quote doesn't shift the columns.  The columns are
+// chosen by CSVOptions.Columns: player columns such as rank and name appear
+// once, per competition columns such as points are repeated for each
+// competition in the order they were listed in oom.conf.
+//
+// ClassicCSV gives the layout the committee spreadsheet has always linked
+// to: a "Year" line and three header rows of competition keys, dates and
+// names, then a row per player
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Column identifies a column of the csv output
+type Column string
+
+// The available columns.  The last four are per competition columns
+const (
+	ColRank      Column = "rank"      // rank e.g. 3, T3 or 3*
+	ColName      Column = "name"      // player name
+	ColID        Column = "id"        // playerid on the website
+	ColTotal     Column = "total"     // oom points counted
+	ColPlayed    Column = "played"    // number of competitions played
+	ColCounted   Column = "counted"   // number of scores counted
+	ColToImprove Column = "toImprove" // a new score must beat this to count
+	ColTieBreak  Column = "tiebreak"  // tie-breaker that decided the rank
+	ColPoints    Column = "points"    // oom points, in brackets if dropped
+	ColPosition  Column = "position"  // finishing position e.g. 3rd
+	ColResult    Column = "result"    // score as displayed on the website
+	ColDetail    Column = "detail"    // points, position and result e.g. 24 (3rd 38)
+)
+
+var columnLabels = map[Column]string{
+	ColRank:      "rank",
+	ColName:      "name",
+	ColID:        "playerid",
+	ColTotal:     "oomPts",
+	ColPlayed:    "#Comp",
+	ColCounted:   "#Counted",
+	ColToImprove: "toImprove",
+	ColTieBreak:  "tiebreak",
+	ColPoints:    "points",
+	ColPosition:  "position",
+	ColResult:    "result",
+	ColDetail:    "detail",
+}
+
+// perComp returns true if the column is repeated for each competition
+func (c Column) perComp() bool {
+	return c == ColPoints || c == ColPosition || c == ColResult || c == ColDetail
+}
+
+// ParseColumns converts a comma separated list of column names e.g.
+// "rank,name,total,points" in to a []Column
+func ParseColumns(s string) ([]Column, error) {
+	var ret []Column
+	for _, name := range strings.Split(s, ",") {
+		col := Column(strings.TrimSpace(name))
+		if col == "" {
+			continue
+		}
+		if _, ok := columnLabels[col]; !ok {
+			return nil, errors.New("oom: unknown column " + name)
+		}
+		ret = append(ret, col)
+	}
+	return ret, nil
+}
+
+// CSVOptions controls the csv output
+type CSVOptions struct {
+	Columns []Column // see ParseColumns
+	Comma   rune     // field delimiter, default ','
+	Classic bool     // year line and key, date and name header rows
+}
+
+// ClassicCSV returns the options for the layout expected by the committee
+// spreadsheet.  If detail is true each score also shows the player's
+// position and result in that competition
+func ClassicCSV(detail bool) CSVOptions {
+	scores := ColPoints
+	if detail {
+		scores = ColDetail
+	}
+	return CSVOptions{
+		Columns: []Column{ColRank, ColName, ColTotal, ColPlayed, scores, ColToImprove},
+		Classic: true,
+	}
+}
+
+// WriteCSV writes the standings in the classic layout, see ClassicCSV.
+// When tie-breakers are in use a final column names the one that decided
+// each player's rank
+func (s *Standings) WriteCSV(w io.Writer, detail bool) error {
+	opts := ClassicCSV(detail)
+	if len(s.Options.TieBreakers) > 0 {
+		opts.Columns = append(opts.Columns, ColTieBreak)
+	}
+	return s.WriteCSVWith(w, opts)
+}
+
+// WriteCSVWith writes the standings using the columns and layout in opts.
+// Provisional players follow in a separate section
+func (s *Standings) WriteCSVWith(w io.Writer, opts CSVOptions) error {
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+	cols := s.csvColumns(opts.Columns)
+	if opts.Classic {
+		year := []string{fmt.Sprintf("Year %d", s.Year)}
+		if incomplete := s.Incomplete(); incomplete != "" {
+			year = append(year, incomplete)
+		}
+		cw.Write(year)
+		cw.Write(csvHeader(cols, false, func(c csvColumn) string { return c.comp.Key }))
+		cw.Write(csvHeader(cols, false, func(c csvColumn) string { return c.comp.Date }))
+	}
+	single := numPerComp(opts.Columns) == 1
+	cw.Write(csvHeader(cols, true, func(c csvColumn) string {
+		if single {
+			return c.comp.Name
+		}
+		return c.comp.Name + " " + columnLabels[c.col]
+	}))
+	for _, player := range s.RankedPlayers {
+		cw.Write(csvRow(cols, s.OOMResults[player]))
+	}
+	if len(s.ProvisionalPlayers) > 0 {
+		cw.Write([]string{fmt.Sprintf("Provisional - fewer than %d competitions",
+			s.Options.MinComps)})
+	}
+	for _, player := range s.ProvisionalPlayers {
+		cw.Write(csvRow(cols, s.OOMResults[player]))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvColumn is a single output column; comp is nil for player columns
+type csvColumn struct {
+	col  Column
+	comp *Competition
+}
+
+// csvColumns expands the per competition columns, which are grouped
+// together where the first of them was listed
+func (s *Standings) csvColumns(cols []Column) []csvColumn {
+	var ret []csvColumn
+	var perComp []Column
+	for _, col := range cols {
+		if col.perComp() {
+			perComp = append(perComp, col)
+		}
+	}
+	expanded := false
+	for _, col := range cols {
+		if !col.perComp() {
+			ret = append(ret, csvColumn{col: col})
+			continue
+		}
+		if expanded {
+			continue
+		}
+		expanded = true
+		for i := range s.Competitions {
+			for _, pc := range perComp {
+				ret = append(ret, csvColumn{col: pc, comp: &s.Competitions[i]})
+			}
+		}
+	}
+	return ret
+}
+
+func numPerComp(cols []Column) int {
+	n := 0
+	for _, col := range cols {
+		if col.perComp() {
+			n++
+		}
+	}
+	return n
+}
+
+// csvHeader returns a header row, using compLabel for the competition
+// columns.  The player columns are left blank unless labelPlayer is true
+func csvHeader(cols []csvColumn, labelPlayer bool, compLabel func(csvColumn) string) []string {
+	var row []string
+	for _, c := range cols {
+		switch {
+		case c.comp != nil:
+			row = append(row, compLabel(c))
+		case labelPlayer:
+			row = append(row, columnLabels[c.col])
+		default:
+			row = append(row, "")
+		}
+	}
+	return row
+}
+
+func csvRow(cols []csvColumn, p PlayerOOM) []string {
+	var row []string
+	for _, c := range cols {
+		row = append(row, csvCell(c, p))
+	}
+	return row
+}
+
+func csvCell(c csvColumn, p PlayerOOM) string {
+	switch c.col {
+	case ColRank:
+		return p.RankString()
+	case ColName:
+		return p.Name
+	case ColID:
+		return p.ID
+	case ColTotal:
+		return strconv.Itoa(p.OOMPoints)
+	case ColPlayed:
+		return strconv.Itoa(p.NumCompetitions)
+	case ColCounted:
+		return strconv.Itoa(p.NumCounted)
+	case ColToImprove:
+		return strconv.Itoa(p.ToImprove)
+	case ColTieBreak:
+		return p.TieBreak.String()
+	}
+	r, ok := p.PlayerByComp[c.comp.Key]
+	if !ok {
+		return ""
+	}
+	switch c.col {
+	case ColPoints:
+		return formatPlayerResult(r, false)
+	case ColPosition:
+		return ordinal(r.Rank)
+	case ColResult:
+		return r.Result
+	case ColDetail:
+		return formatPlayerResult(r, true)
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package oom
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteCSVWith(t *testing.T) {
+	comps := []Competition{
+		testComp("1", "Sat 2nd Apr '16", "Ann", "Bea"),
+		testComp("2", "Sat 9th Apr '16", "Bea"),
+	}
+	comps[1].Name = `Hospice and St Helena, Day "2"`
+	s := NewStandings(2016, comps, Options{MaxComps: 1})
+
+	var buf bytes.Buffer
+	if err := s.WriteCSV(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+	want := "Year 2016\n" +
+		",,,,1,2,\n" +
+		",,,,Sat 2nd Apr '16,Sat 9th Apr '16,\n" +
+		"rank,name,oomPts,#Comp,comp 1,\"Hospice and St Helena, Day \"\"2\"\"\",toImprove\n" +
+		"1,Ann,2,1,2,,2\n" +
+		"2,Bea,1,2,1,[1],1\n"
+	if buf.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, buf.String())
+	}
+
+	buf.Reset()
+	cols, _ := ParseColumns("rank,name,points,position")
+	if err := s.WriteCSVWith(&buf, CSVOptions{Columns: cols, Comma: ';'}); err != nil {
+		t.Fatal(err)
+	}
+	want = "rank;name;comp 1 points;comp 1 position;" +
+		"\"Hospice and St Helena, Day \"\"2\"\" points\";\"Hospice and St Helena, Day \"\"2\"\" position\"\n" +
+		"1;Ann;2;1st;;\n" +
+		"2;Bea;1;2nd;[1];1st\n"
+	if buf.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, buf.String())
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	if _, err := ParseColumns("rank,handicap"); err == nil {
+		t.Error("expected error for unknown column")
+	}
+}
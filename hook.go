@@ -0,0 +1,197 @@
+package oom
+
+// hook.go notifies the committee of changes in the standings.  A Notice
+// describes what changed between two sets of standings - a new leader,
+// changes in the top places and the new competitions included - and Hooks
+// deliver it, POSTing it as json to a URL or running a local command with
+// the notice as text on stdin and as json in $OOM_NOTICE.  Failed
+// deliveries are retried, with the delay doubling each time
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// NoticeSchema identifies the version of the Notice json
+const NoticeSchema = "oom-notice/1"
+
+// Notice describes the changes in the standings worth telling people about
+type Notice struct {
+	Schema     string       `json:"schema"`
+	Year       int          `json:"year"`
+	Generated  time.Time    `json:"generated"`
+	NewComps   []seriesComp `json:"newComps"`
+	Leader     string       `json:"leader"`
+	PrevLeader string       `json:"previousLeader"`
+	NewLeader  bool         `json:"newLeader"`
+	Top        int          `json:"top"`
+	TopChanges []Change     `json:"topChanges"` // changes in the top places, in the new rank order
+}
+
+// NewNotice returns the changes from old to new, in the top places only
+func NewNotice(old *Standings, new *Standings, top int) Notice {
+	n := Notice{Schema: NoticeSchema, Year: new.Year, Generated: time.Now().UTC(),
+		NewComps: []seriesComp{}, Top: top, TopChanges: []Change{}}
+	d := Diff(old, new)
+	for _, c := range d.NewComps {
+		n.NewComps = append(n.NewComps, seriesComp{Key: c.Key, Name: c.Name, Date: c.Date,
+			URL: c.URL, Major: c.Major, NumPlayers: c.NumPlayers})
+	}
+	if len(new.RankedPlayers) > 0 {
+		n.Leader = new.RankedPlayers[0]
+	}
+	if len(old.RankedPlayers) > 0 {
+		n.PrevLeader = old.RankedPlayers[0]
+	}
+	n.NewLeader = n.Leader != n.PrevLeader
+	inTop := func(s *Standings, name string) bool {
+		for i, ranked := range s.RankedPlayers {
+			if i >= top {
+				break
+			}
+			if ranked == name {
+				return true
+			}
+		}
+		return false
+	}
+	for _, c := range d.Changes {
+		if inTop(new, c.Name) || inTop(old, c.Name) {
+			n.TopChanges = append(n.TopChanges, c)
+		}
+	}
+	return n
+}
+
+// Empty returns true if there is nothing to tell
+func (n Notice) Empty() bool {
+	return len(n.NewComps) == 0 && !n.NewLeader && len(n.TopChanges) == 0
+}
+
+// WriteText writes the notice as plain text
+func (n Notice) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "Order of Merit %d\n", n.Year)
+	if n.NewLeader && n.Leader != "" {
+		if n.PrevLeader != "" {
+			fmt.Fprintf(w, "New leader: %s, taking over from %s\n", n.Leader, n.PrevLeader)
+		} else {
+			fmt.Fprintf(w, "Leader: %s\n", n.Leader)
+		}
+	}
+	var d StandingsDiff
+	for _, c := range n.NewComps {
+		d.NewComps = append(d.NewComps, Competition{Key: c.Key, Name: c.Name, Date: c.Date})
+	}
+	d.Changes = n.TopChanges
+	fmt.Fprintf(w, "Changes in the top %d:\n", n.Top)
+	return d.WriteText(w)
+}
+
+// Hooks deliver notices to URLs and commands
+type Hooks struct {
+	Targets []string      // http(s) URLs to POST to, otherwise commands run by sh
+	Retries int           // times a failed delivery is retried
+	Backoff time.Duration // delay before the first retry, doubling after
+	DryRun  bool          // write what would be delivered to Log instead
+	Log     io.Writer     // where dry runs are written, default stdout
+	Client  *http.Client  // default has a 30 second timeout
+}
+
+// Notify delivers the notice to every target, returning the last error if
+// any delivery failed after its retries
+func (h *Hooks) Notify(n Notice) error {
+	logw := h.Log
+	if logw == nil {
+		logw = os.Stdout
+	}
+	client := h.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	body, err := json.MarshalIndent(n, "", "  ")
+	if err != nil {
+		return err
+	}
+	var text bytes.Buffer
+	n.WriteText(&text)
+
+	var last error
+	for _, target := range h.Targets {
+		if h.DryRun {
+			fmt.Fprintf(logw, "dry run: would notify %s of\n%s", target, text.String())
+			continue
+		}
+		delay := h.Backoff
+		for attempt := 0; ; attempt++ {
+			if isURL(target) {
+				err = postNotice(client, target, body)
+			} else {
+				err = runNotice(target, text.Bytes(), body)
+			}
+			if err == nil || attempt >= h.Retries || permanent(err) {
+				break
+			}
+			Logger.Warn("notify failed, retrying", "target", target, "err", err, "wait", delay)
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if err != nil {
+			Logger.Error("notify failed", "target", target, "err", err)
+			last = err
+		}
+	}
+	return last
+}
+
+func isURL(target string) bool {
+	return strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://")
+}
+
+// statusError is a non-2xx response to a POST
+type statusError struct {
+	url    string
+	status int
+}
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("%s returned %d %s", e.url, e.status, http.StatusText(e.status))
+}
+
+// permanent returns true if retrying won't help, the request having been
+// rejected as bad
+func permanent(err error) bool {
+	se, ok := err.(statusError)
+	return ok && se.status >= 400 && se.status < 500 && se.status != http.StatusTooManyRequests
+}
+
+func postNotice(client *http.Client, url string, body []byte) error {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return statusError{url, resp.StatusCode}
+	}
+	return nil
+}
+
+func runNotice(command string, text []byte, body []byte) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(text)
+	cmd.Env = append(os.Environ(), "OOM_NOTICE="+string(body))
+	out, err := cmd.CombinedOutput()
+	if err != nil && len(out) > 0 {
+		return fmt.Errorf("%v: %s", err, bytes.TrimSpace(out))
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+package oom
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewNotice(t *testing.T) {
+	comps := []Competition{
+		testComp("1", "Sat 2nd Apr '16", "Ann", "Bea", "Cat", "Dot"),
+		testComp("2", "Sat 9th Apr '16", "Bea", "Dot", "Cat"),
+	}
+	old := NewStandings(2016, comps[:1], Options{})
+	n := NewNotice(old, NewStandings(2016, comps, Options{}), 2)
+	if !n.NewLeader || n.Leader != "Bea" || n.PrevLeader != "Ann" {
+		t.Errorf("expected Bea the new leader, got %+v", n)
+	}
+	if len(n.NewComps) != 1 || n.NewComps[0].Key != "2" {
+		t.Errorf("expected comp 2 new, got %v", n.NewComps)
+	}
+	// Bea and Ann swap places, Cat and Dot are outside the top 2
+	var names []string
+	for _, c := range n.TopChanges {
+		names = append(names, c.Name)
+	}
+	if strings.Join(names, ",") != "Bea,Ann" {
+		t.Errorf("expected changes for Bea and Ann, got %v", names)
+	}
+	if n.Empty() || !NewNotice(old, old, 2).Empty() {
+		t.Error("expected a notice only when the standings change")
+	}
+	var buf bytes.Buffer
+	n.WriteText(&buf)
+	if !strings.Contains(buf.String(), "New leader: Bea, taking over from Ann\n") {
+		t.Errorf("expected the new leader in\n%s", buf.String())
+	}
+}
+
+func TestHooks(t *testing.T) {
+	comps := []Competition{
+		testComp("1", "Sat 2nd Apr '16", "Ann", "Bea"),
+		testComp("2", "Sat 9th Apr '16", "Bea", "Cat"),
+	}
+	n := NewNotice(NewStandings(2016, comps[:1], Options{}), NewStandings(2016, comps, Options{}), 10)
+
+	// fails twice before accepting the notice
+	calls := 0
+	var got Notice
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= 2 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+	rejects := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no", http.StatusBadRequest)
+	}))
+	defer rejects.Close()
+
+	dir, err := ioutil.TempDir("", "oomhook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "notice.txt")
+
+	var log bytes.Buffer
+	defer func(l *slog.Logger) { Logger = l }(Logger)
+	Logger = slog.New(slog.NewJSONHandler(&log, nil))
+	h := Hooks{Targets: []string{srv.URL, "cat > " + out}, Retries: 2}
+	if err := h.Notify(n); err != nil {
+		t.Fatalf("%v\n%s", err, log.String())
+	}
+	if strings.Count(log.String(), `"msg":"notify failed, retrying"`) != 2 {
+		t.Errorf("expected the 2 retries logged, got\n%s", log.String())
+	}
+	if calls != 3 || got.Schema != NoticeSchema || got.Leader != "Bea" {
+		t.Errorf("expected the notice on the 3rd attempt, got %d attempts and %+v", calls, got)
+	}
+	if text, _ := ioutil.ReadFile(out); !strings.Contains(string(text), "New leader: Bea") {
+		t.Errorf("expected the notice as text, got %s", text)
+	}
+
+	// a bad request isn't retried
+	log.Reset()
+	h = Hooks{Targets: []string{rejects.URL}, Retries: 2}
+	if err := h.Notify(n); err == nil || strings.Contains(log.String(), "retrying") ||
+		!strings.Contains(log.String(), `"msg":"notify failed"`) {
+		t.Errorf("expected the bad request to fail without retries, got %v\n%s", err, log.String())
+	}
+
+	calls = 0
+	log.Reset()
+	h = Hooks{Targets: []string{srv.URL}, DryRun: true, Log: &log}
+	if err := h.Notify(n); err != nil || calls != 0 || !strings.Contains(log.String(), "dry run") {
+		t.Errorf("expected a dry run, got %v after %d calls\n%s", err, calls, log.String())
+	}
+}
@@ -0,0 +1,281 @@
+package oom
+
+// html.go renders the standings as a self-contained static site, for the
+// club noticeboard screen and members' phones:
+// - index.html: the standings
+// - comp-KEY.html: a page per competition with the full results
+// - player-NAME.html: a page per player with their statement, see
+//   statement.go
+// - charts.html: the rank, points and field size charts, see chart.go
+// Tables can be sorted by clicking a column heading, and print without the
+// navigation links.
+//
+// The pages are rendered with html/template.  The club can override any of
+// layout.html (the header and footer shared by every page), standings.html,
+// competition.html, player.html and charts.html by putting a file of that name in the
+// templates directory passed to WriteHTML - see the defaults below for the
+// data available to each
+
+import (
+	"bytes"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// htmlPage is the data passed to each template
+type htmlPage struct {
+	Title     string
+	Standings *Standings
+	Comp      *Competition // competition.html only
+	Results   []PlayerResult
+	Player    *PlayerOOM // player.html only
+	Statement *Statement
+	Charts    []htmlChart // charts.html only
+}
+
+// htmlChart is an SVG chart embedded in charts.html
+type htmlChart struct {
+	Title string
+	SVG   template.HTML
+}
+
+// PlayerFile returns the name of the player's page e.g. player-jo-mager.html
+func PlayerFile(name string) string {
+	return "player-" + slug(name) + ".html"
+}
+
+// CompFile returns the name of the competition's page e.g. comp-1266.html
+func CompFile(key string) string {
+	return "comp-" + slug(key) + ".html"
+}
+
+// slug lower cases s and replaces anything but letters and digits with -
+func slug(s string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '-'
+	}, s), "-")
+}
+
+var htmlFuncs = template.FuncMap{
+	"ordinal":    ordinal,
+	"points":     func(r PlayerResult) string { return formatPlayerResult(r, false) },
+	"playerFile": PlayerFile,
+	"compFile":   CompFile,
+	"entered": func(p PlayerOOM, key string) bool {
+		_, ok := p.PlayerByComp[key]
+		return ok
+	},
+	"result": func(p PlayerOOM, key string) PlayerResult { return p.PlayerByComp[key] },
+	"players": func(s *Standings) []PlayerOOM {
+		var ret []PlayerOOM
+		for _, names := range [][]string{s.RankedPlayers, s.ProvisionalPlayers} {
+			for _, name := range names {
+				ret = append(ret, s.OOMResults[name])
+			}
+		}
+		return ret
+	},
+}
+
+// htmlTemplates returns the default templates, overridden by any found
+// in templateDir
+func htmlTemplates(templateDir string) (*template.Template, error) {
+	t := template.New("site").Funcs(htmlFuncs)
+	for _, name := range []string{"layout.html", "standings.html", "competition.html", "player.html", "charts.html"} {
+		text := defaultTemplates[name]
+		if templateDir != "" {
+			data, err := ioutil.ReadFile(filepath.Join(templateDir, name))
+			if err == nil {
+				text = string(data)
+			} else if !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+		if _, err := t.New(name).Parse(text); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// sitePage is a page of the site, rendered with a template
+type sitePage struct {
+	template string
+	page     htmlPage
+}
+
+// site returns the pages of the site keyed by file name
+func (s *Standings) site() (map[string]sitePage, error) {
+	pages := make(map[string]sitePage)
+	title := "Order of Merit " + strconv.Itoa(s.Year)
+	pages["index.html"] = sitePage{"standings.html", htmlPage{Title: title, Standings: s}}
+	for i := range s.Competitions {
+		comp := &s.Competitions[i]
+		pages[CompFile(comp.Key)] = sitePage{"competition.html", htmlPage{
+			Title: comp.Name, Standings: s, Comp: comp, Results: comp.byRank()}}
+	}
+	history := s.History()
+	var charts []htmlChart
+	for _, c := range []*chart{s.rankChart(history, ChartOptions{}),
+		s.pointsChart(history, ChartOptions{}), s.fieldChart(ChartOptions{})} {
+		var b bytes.Buffer
+		if err := c.write(&b); err != nil {
+			return nil, err
+		}
+		charts = append(charts, htmlChart{Title: c.title, SVG: template.HTML(b.String())})
+	}
+	pages["charts.html"] = sitePage{"charts.html", htmlPage{
+		Title: "Charts " + strconv.Itoa(s.Year), Standings: s, Charts: charts}}
+	for _, names := range [][]string{s.RankedPlayers, s.ProvisionalPlayers} {
+		for _, name := range names {
+			st, err := s.statement(name, history)
+			if err != nil {
+				return nil, err
+			}
+			pages[PlayerFile(name)] = sitePage{"player.html", htmlPage{
+				Title: name, Standings: s, Player: &st.Player, Statement: st}}
+		}
+	}
+	return pages, nil
+}
+
+// WriteHTML renders the standings, competition and player pages in to
+// dir, which is created if need be.  templateDir may be empty to use the
+// default templates
+func (s *Standings) WriteHTML(dir string, templateDir string) error {
+	t, err := htmlTemplates(templateDir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	pages, err := s.site()
+	if err != nil {
+		return err
+	}
+	for fname, p := range pages {
+		f, err := os.Create(filepath.Join(dir, fname))
+		if err != nil {
+			return err
+		}
+		err = t.ExecuteTemplate(f, p.template, p.page)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var defaultTemplates = map[string]string{
+	"layout.html": `{{define "header"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 1em; color: #222; }
+table { border-collapse: collapse; }
+th, td { padding: 0.2em 0.5em; border-bottom: 1px solid #ddd; text-align: right; }
+th { background: #1f5130; color: #fff; cursor: pointer; position: sticky; top: 0; }
+td.name, th.name { text-align: left; }
+tr:nth-child(even) td { background: #f4f7f4; }
+.dropped { color: #999; font-style: italic; }
+.provisional { color: #666; }
+.incomplete { color: #b00; font-weight: bold; }
+a { color: #1f5130; }
+nav { margin-bottom: 1em; }
+.scroll { overflow-x: auto; }
+svg { max-width: 100%; height: auto; }
+@media print {
+  nav, .noprint { display: none; }
+  body { margin: 0; font-size: 9pt; }
+  th { background: none; color: #000; border-bottom: 2px solid #000; position: static; }
+  tr:nth-child(even) td { background: none; }
+  a { color: #000; text-decoration: none; }
+}
+</style>
+</head>
+<body>
+<nav><a href="index.html">Order of Merit {{.Standings.Year}}</a> | <a href="charts.html">Charts</a></nav>
+<h1>{{.Title}}</h1>
+{{with .Standings.Incomplete}}<p class="incomplete">{{.}}</p>
+{{end}}{{end}}
+{{define "footer"}}
+<script>
+// click a column heading to sort, click again to reverse
+document.querySelectorAll("table.sortable th").forEach(function(th) {
+  th.addEventListener("click", function() {
+    var table = th.closest("table"), body = table.tBodies[0], col = th.cellIndex;
+    var asc = th.dataset.sort !== "asc";
+    table.querySelectorAll("th").forEach(function(h) { delete h.dataset.sort; });
+    th.dataset.sort = asc ? "asc" : "desc";
+    var key = function(tr) {
+      var s = tr.cells[col] ? tr.cells[col].textContent.trim() : "";
+      var n = parseFloat(s.replace(/^[T\[]+/, ""));
+      return isNaN(n) ? s.toLowerCase() : n;
+    };
+    Array.from(body.rows).sort(function(a, b) {
+      var ka = key(a), kb = key(b);
+      var c = ka < kb ? -1 : ka > kb ? 1 : 0;
+      return asc ? c : -c;
+    }).forEach(function(tr) { body.appendChild(tr); });
+  });
+});
+</script>
+</body>
+</html>
+{{end}}`,
+
+	"standings.html": `{{template "header" .}}{{$s := .Standings}}
+<div class="scroll">
+<table class="sortable">
+<thead><tr><th>Rank</th><th class="name">Name</th><th>Points</th><th>Played</th><th>Counted</th><th>To improve</th>{{if $s.Options.TieBreakers}}<th class="name">Tie-break</th>{{end}}
+{{range $s.Competitions}}<th title="{{.Date}}"><a href="{{compFile .Key}}">{{.Name}}</a></th>{{end}}</tr></thead>
+<tbody>
+{{range $p := players $s}}<tr{{if $p.Provisional}} class="provisional"{{end}}><td>{{$p.RankString}}</td><td class="name"><a href="{{playerFile $p.Name}}">{{$p.Name}}</a></td><td>{{$p.OOMPoints}}</td><td>{{$p.NumCompetitions}}</td><td>{{$p.NumCounted}}</td><td>{{$p.ToImprove}}</td>{{if $s.Options.TieBreakers}}<td class="name">{{$p.TieBreak}}</td>{{end}}
+{{range $s.Competitions}}{{if entered $p .Key}}{{$r := result $p .Key}}<td{{if not $r.Counted}} class="dropped"{{end}}>{{points $r}}</td>{{else}}<td></td>{{end}}{{end}}</tr>
+{{end}}</tbody>
+</table>
+</div>
+{{if $s.ProvisionalPlayers}}<p class="provisional">* provisional - fewer than {{$s.Options.MinComps}} competitions played</p>{{end}}
+<p>Dropped scores are shown <span class="dropped">[in brackets]</span>.</p>
+{{template "footer" .}}`,
+
+	"competition.html": `{{template "header" .}}
+<p>{{.Comp.Date}} - {{.Comp.NumPlayers}} players</p>
+<table class="sortable">
+<thead><tr><th>Position</th><th class="name">Name</th><th>Result</th><th>Points</th></tr></thead>
+<tbody>
+{{range .Results}}<tr><td>{{.Rank}}</td><td class="name"><a href="{{playerFile .Name}}">{{.Name}}</a></td><td>{{.Result}}</td><td>{{.OOMPoints}}</td></tr>
+{{end}}</tbody>
+</table>
+{{template "footer" .}}`,
+
+	"player.html": `{{template "header" .}}{{$p := .Player}}
+<p>Rank {{$p.RankString}} with {{$p.OOMPoints}} points from {{$p.NumCounted}} of {{$p.NumCompetitions}} competitions{{if $p.ToImprove}} - a score of more than {{$p.ToImprove}} points will improve the total{{end}}</p>
+<table class="sortable">
+<thead><tr><th class="name">Date</th><th class="name">Competition</th><th>Result</th><th>Position</th><th>Field</th><th>Points</th><th>Total</th><th>Rank</th></tr></thead>
+<tbody>
+{{range .Statement.Lines}}<tr{{if not .Result.Counted}} class="dropped"{{end}}><td class="name">{{.Comp.Date}}</td><td class="name"><a href="{{compFile .Comp.Key}}">{{.Comp.Name}}</a></td><td>{{.Result.Result}}</td><td>{{ordinal .Result.Rank}}</td><td>{{.Comp.NumPlayers}}</td><td>{{points .Result}}</td><td>{{.Total}}</td><td>{{.Rank}}</td></tr>
+{{end}}</tbody>
+</table>
+{{range .Statement.Gaps}}<p>{{.}}</p>
+{{end}}<p>Dropped scores are shown <span class="dropped">[in brackets]</span>.</p>
+{{template "footer" .}}`,
+
+	"charts.html": `{{template "header" .}}
+{{range .Charts}}<h2>{{.Title}}</h2>
+<div class="chart">{{.SVG}}</div>
+{{end}}{{template "footer" .}}`,
+}
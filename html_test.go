@@ -0,0 +1,63 @@
+package oom
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteHTML(t *testing.T) {
+	comps := []Competition{
+		testComp("1", "Sat 2nd Apr '16", "Ann", "Bea"),
+		testComp("2", "Sat 9th Apr '16", "Bea"),
+	}
+	s := NewStandings(2016, comps, Options{MaxComps: 1})
+	dir, err := ioutil.TempDir("", "oomhtml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	templates := filepath.Join(dir, "templates")
+	os.Mkdir(templates, 0755)
+	ioutil.WriteFile(filepath.Join(templates, "competition.html"),
+		[]byte(`club {{.Comp.Name}}`), 0644)
+
+	site := filepath.Join(dir, "site")
+	if err := s.WriteHTML(site, templates); err != nil {
+		t.Fatal(err)
+	}
+	for fname, want := range map[string]string{
+		"index.html":      `<td class="dropped">[1]</td>`,
+		"comp-2.html":     "club comp 2",
+		"player-bea.html": `<a href="comp-2.html">comp 2</a>`,
+		"charts.html":     `<svg xmlns="http://www.w3.org/2000/svg"`,
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(site, fname))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s: expected %s in\n%s", fname, want, data)
+		}
+	}
+
+	// Ann and Bea level on 2 points, Bea having played more
+	s = NewStandings(2016, comps, Options{TieBreakers: []TieBreaker{MostPlayed}})
+	if err := s.WriteHTML(site, templates); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(site, "index.html"))
+	if !strings.Contains(string(data), `<th class="name">Tie-break</th>`) ||
+		!strings.Contains(string(data), `<td>0</td><td class="name">played</td>`) {
+		t.Errorf("expected the tie-breaks shown in\n%s", data)
+	}
+}
+
+func TestPlayerFile(t *testing.T) {
+	if got := PlayerFile("Mary O'Brien"); got != "player-mary-o-brien.html" {
+		t.Errorf("expected player-mary-o-brien.html, got %s", got)
+	}
+}
@@ -0,0 +1,81 @@
+package oom
+
+// json.go writes the standings for other programs: the club app, notebooks
+// and spreadsheets.
+// - WriteJSON writes the whole model as a Document: every competition with
+//   its results, and every player with their per competition results,
+//   counted flags and rank
+// - WriteNDJSON writes one ResultRow per line, a player's result in one
+//   competition, ready to load as a table
+// Both carry JSONSchema so readers can tell which layout they have.  Fields
+// are only ever added within a schema version; renaming or removing a field
+// bumps the version
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// JSONSchema identifies the layout of the json and ndjson output
+const JSONSchema = "oom/1"
+
+// Document is the top level object written by WriteJSON
+type Document struct {
+	Schema    string     `json:"schema"`
+	Generated time.Time  `json:"generated"`
+	Standings *Standings `json:"standings"`
+}
+
+// ResultRow is one player's result in one competition, as written by
+// WriteNDJSON
+type ResultRow struct {
+	Schema      string `json:"schema"`
+	Year        int    `json:"year"`
+	CompKey     string `json:"compKey"`
+	CompName    string `json:"compName"`
+	CompDate    string `json:"compDate"`
+	NumPlayers  int    `json:"numPlayers"`
+	Player      string `json:"player"`
+	PlayerID    string `json:"playerId,omitempty"`
+	Position    int    `json:"position"`
+	Result      string `json:"result"`
+	OOMPoints   int    `json:"oomPoints"`
+	Counted     bool   `json:"counted"`
+	Rank        int    `json:"rank"` // the player's rank in the standings
+	Tied        bool   `json:"tied"`
+	Provisional bool   `json:"provisional"`
+}
+
+// WriteJSON writes the standings as an indented Document
+func (s *Standings) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(Document{Schema: JSONSchema, Generated: time.Now(), Standings: s})
+}
+
+// WriteNDJSON writes a ResultRow per line, players in rank order and each
+// player's results in competition order
+func (s *Standings) WriteNDJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, names := range [][]string{s.RankedPlayers, s.ProvisionalPlayers} {
+		for _, name := range names {
+			p := s.OOMResults[name]
+			for _, comp := range s.Competitions {
+				r, ok := p.PlayerByComp[comp.Key]
+				if !ok {
+					continue
+				}
+				if err := enc.Encode(ResultRow{Schema: JSONSchema, Year: s.Year,
+					CompKey: comp.Key, CompName: comp.Name, CompDate: comp.Date,
+					NumPlayers: comp.NumPlayers, Player: name, PlayerID: p.ID,
+					Position: r.Rank, Result: r.Result, OOMPoints: r.OOMPoints,
+					Counted: r.Counted, Rank: p.Rank, Tied: p.Tied,
+					Provisional: p.Provisional}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
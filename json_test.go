@@ -0,0 +1,52 @@
+package oom
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteJSON(t *testing.T) {
+	comps := []Competition{
+		testComp("1", "Sat 2nd Apr '16", "Ann", "Bea"),
+		testComp("2", "Sat 9th Apr '16", "Bea"),
+	}
+	s := NewStandings(2016, comps, Options{MaxComps: 1,
+		TieBreakers: []TieBreaker{MostPlayed}, Period: Quarter})
+	var buf bytes.Buffer
+	if err := s.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc Document
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Schema != JSONSchema || doc.Standings.Options.TieBreakers[0] != MostPlayed ||
+		doc.Standings.Options.Period != Quarter {
+		t.Errorf("expected schema and options to round trip, got %+v", doc)
+	}
+	bea := doc.Standings.OOMResults["Bea"]
+	if bea.Rank != 2 || !bea.PlayerByComp["1"].Counted || bea.PlayerByComp["2"].Counted {
+		t.Errorf("expected Bea ranked 2 counting comp 1 only, got %+v", bea)
+	}
+	if !strings.Contains(buf.String(), `"tieBreakers": [
+        "played"
+      ]`) {
+		t.Errorf("expected tie-breakers by name, got\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := s.WriteNDJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 rows, got\n%s", buf.String())
+	}
+	var row ResultRow
+	json.Unmarshal([]byte(lines[2]), &row)
+	if row.Player != "Bea" || row.CompKey != "2" || row.Counted || row.Rank != 2 {
+		t.Errorf("expected Bea's dropped result in comp 2, got %+v", row)
+	}
+}
@@ -0,0 +1,63 @@
+package oom
+
+// load.go loads many competitions at once, as fetching each from the
+// website takes a while
+
+import (
+	"context"
+	"sync"
+)
+
+// LoadOptions control LoadAll
+type LoadOptions struct {
+	Concurrency int // competitions loaded at once, default 10
+	// Progress, if set, is called as each competition is loaded, or fails
+	// to load, with the number done so far.  It is called by one goroutine
+	// at a time
+	Progress func(done int, total int, comp *Competition, err error)
+}
+
+// LoadAll loads the competitions, as Load, several at a time, returning the
+// error loading each competition, nil if it loaded.  Cancelling ctx stops
+// the loads in progress and those not yet started, their errors being
+// ctx.Err()
+func (c *Client) LoadAll(ctx context.Context, comps []Competition, opts LoadOptions) []error {
+	n := opts.Concurrency
+	if n <= 0 {
+		n = 10
+	}
+	errs := make([]error, len(comps))
+	var progress sync.Mutex
+	done := 0
+	report := func(i int) {
+		progress.Lock()
+		defer progress.Unlock()
+		done++
+		if opts.Progress != nil {
+			opts.Progress(done, len(comps), &comps[i], errs[i])
+		}
+	}
+
+	slots := make(chan struct{}, n) // max concurrent calls to LoadContext
+	var wg sync.WaitGroup
+	for i := range comps {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			errs[i] = err
+			report(i)
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = c.LoadContext(ctx, &comps[i])
+			report(i)
+			<-slots
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
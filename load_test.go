@@ -0,0 +1,101 @@
+package oom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// compPage is a competition's results page with two players
+const compPage = `<table>
+<tr><td><a href="player.php?playerid=101">Ann Able</a>(12)</td>
+<td><a href="viewround.php?roundid=1">38</a></td></tr>
+<tr><td><a href="player.php?playerid=102">Bea Baker</a>(20)</td>
+<td><a href="viewround.php?roundid=2">35</a></td></tr>
+</table>`
+
+func TestLoadAll(t *testing.T) {
+	data, err := ioutil.ReadFile("1266.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newTestClient(t)
+	c.Retries = 0
+	dir := c.CacheDir
+	ioutil.WriteFile(filepath.Join(dir, "1266.txt"), data, 0644)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("compid") != "777" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, compPage)
+	}))
+	defer ts.Close()
+
+	comps := []Competition{
+		{Key: "1266"},
+		{Key: "777", URL: ts.URL + "/competition.php?compid=777"},
+		{Key: "888", URL: ts.URL + "/competition.php?compid=888"},
+	}
+	var calls []int
+	errs := c.LoadAll(context.Background(), comps, LoadOptions{Concurrency: 2,
+		Progress: func(done, total int, comp *Competition, err error) {
+			if total != 3 {
+				t.Errorf("expected a total of 3, got %d", total)
+			}
+			calls = append(calls, done)
+		}})
+	if errs[0] != nil || comps[0].NumPlayers != 68 {
+		t.Errorf("1266: expected 68 players from the cache, got %d %v", comps[0].NumPlayers, errs[0])
+	}
+	if errs[1] != nil || comps[1].NumPlayers != 2 || comps[1].Results["Bea Baker"].OOMPoints != 1 {
+		t.Errorf("777: expected 2 players from the web, got %d %v", comps[1].NumPlayers, errs[1])
+	}
+	if _, err := os.Stat(filepath.Join(dir, "777.txt")); err != nil {
+		t.Errorf("777: expected it cached, got %v", err)
+	}
+	if errs[2] == nil {
+		t.Errorf("888: expected a 404 error")
+	}
+	if fmt.Sprint(calls) != "[1 2 3]" {
+		t.Errorf("expected progress [1 2 3], got %v", calls)
+	}
+}
+
+func TestLoadAllCancel(t *testing.T) {
+	c := newTestClient(t)
+	c.Timeout = 10 * time.Second
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	var comps []Competition
+	for i := 0; i < 5; i++ {
+		comps = append(comps, Competition{Key: fmt.Sprint(i + 1), URL: fmt.Sprintf("%s/?compid=%d", ts.URL, i+1)})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	errs := c.LoadAll(ctx, comps, LoadOptions{Concurrency: 2})
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the loads abandoned, took %v", elapsed)
+	}
+	for i, err := range errs {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("competition %d: expected deadline exceeded, got %v", i+1, err)
+		}
+	}
+}
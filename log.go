@@ -0,0 +1,13 @@
+package oom
+
+// log.go has the package's structured logger, so scheduled runs can log
+// json and choose how much is logged: pages fetched at debug level, logins,
+// new results and skipped players at info, retries and problems at warn
+
+import (
+	"log/slog"
+)
+
+// Logger logs what the package is doing, by default as the log package
+// does
+var Logger = slog.Default()
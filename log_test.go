@@ -0,0 +1,49 @@
+package oom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	var b bytes.Buffer
+	defer func(l *slog.Logger) { Logger = l }(Logger)
+	Logger = slog.New(slog.NewJSONHandler(&b, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	c := newTestClient(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, strings.Replace(compPage, "(20)", "(40)", 1))
+	}))
+	defer ts.Close()
+
+	comp := Competition{Key: "777", URL: ts.URL + "/competition.php?compid=777"}
+	if err := c.populateResultsFromWeb(context.Background(), &comp); err != nil {
+		t.Fatal(err)
+	}
+	if comp.NumPlayers != 1 {
+		t.Errorf("expected 1 player, got %d", comp.NumPlayers)
+	}
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		var r map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("expected json lines, got %q: %v", line, err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %v", records)
+	}
+	if r := records[0]; r["level"] != "DEBUG" || r["msg"] != "fetching page" || r["url"] != comp.URL {
+		t.Errorf("expected the page fetched at debug level, got %v", r)
+	}
+	if r := records[1]; r["level"] != "INFO" || r["player"] != "Bea Baker" || r["handicap"] != 40.0 {
+		t.Errorf("expected Bea Baker omitted at info level, got %v", r)
+	}
+}
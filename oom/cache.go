@@ -0,0 +1,74 @@
+package main
+
+// cache.go has the cache command, listing or purging the cached files
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"matt/oom"
+	"os"
+	"text/tabwriter"
+)
+
+// cache lists the cached files or, given purge, removes those of the
+// competitions named by key, all competitions with --all, and the lists
+// of competitions with --lists
+func cache(args []string) {
+	fs, s := newCommand("cache", "[list | purge [KEY...]]", "-", "file to write the list to, - for stdout")
+	lists := fs.Bool("lists", false, "purge: remove the cached lists of competitions, so they are fetched again")
+	words := parseArgs(fs, args)
+	action := "list"
+	if len(words) > 0 {
+		action, words = words[0], words[1:]
+	}
+	s.apply()
+	files, err := s.client.ReadCache()
+	if err != nil {
+		fatal(err)
+	}
+	switch action {
+	case "list":
+		writeFile(*s.out, func(w io.Writer) error {
+			tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+			fmt.Fprintln(tw, "File\tSize\tModified\tContents")
+			for _, f := range files {
+				contents := fmt.Sprintf("competitions in %d", f.Year)
+				if f.Comp != nil {
+					contents = fmt.Sprintf("%s %s, %d players", f.Comp.Date, f.Comp.Name, f.Comp.NumPlayers)
+				}
+				if f.Page != nil && f.Page.Amended() {
+					contents += fmt.Sprintf(", amended %s", f.Page.Changed.Local().Format("2006-01-02 15:04"))
+				}
+				fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", f.Path, f.Size,
+					f.ModTime.Format("2006-01-02 15:04"), contents)
+			}
+			return tw.Flush()
+		})
+	case "purge":
+		keys := make(map[string]bool)
+		for _, key := range words {
+			keys[key] = true
+		}
+		if len(keys) == 0 && !*s.all && !*lists {
+			fatal(errors.New("cache: nothing to purge, give competition keys, --all or --lists"))
+		}
+		for _, f := range files {
+			if (f.Comp == nil && *lists) || (f.Comp != nil && (*s.all || keys[f.Comp.Key])) {
+				if err := f.Remove(); err != nil {
+					fatal(err)
+				}
+				oom.Logger.Info("removed", "file", f.Path)
+				if f.Comp != nil {
+					delete(keys, f.Comp.Key)
+				}
+			}
+		}
+		for key := range keys {
+			oom.Logger.Warn("competition is not cached", "key", key)
+		}
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+}
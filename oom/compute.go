@@ -0,0 +1,88 @@
+package main
+
+// compute.go has the compute command, writing the standings as csv
+
+import (
+	"errors"
+	"io"
+	"matt/oom"
+	"unicode/utf8"
+)
+
+func compute(args []string) {
+	computeCmd("compute", args, false)
+}
+
+// computeCmd writes the standings as csv, and optionally xlsx.  The
+// competitions are fetched if need be when fetch is true, otherwise the
+// command is offline and only those cached are included
+func computeCmd(name string, args []string, fetch bool) {
+	fs, s := newCommand(name, "", "out.csv", "csv file to write, - for stdout")
+	rank := addRankFlags(fs)
+	detail := fs.Bool("detail", false, "set to true to output player rank and result additional to oom points")
+	xlsx := fs.String("xlsx", "", "also write the standings to this .xlsx workbook")
+	delim := fs.String("delim", ",", "csv field delimiter, \"tab\" for tab")
+	preset := fs.String("preset", "classic", "csv layout: classic for the spreadsheet or flat for a single header row")
+	columns := fs.String("columns", "", "csv columns from rank,name,id,total,played,counted,toImprove,tiebreak and per competition points,position,result,detail (default per preset)")
+	snapshots := addSnapshotFlags(fs, s)
+	hooks := addHookFlags(fs)
+	fs.Parse(args)
+	if !fetch {
+		*s.offline = true
+	}
+	s.apply()
+	opts := rank.options()
+	csvOpts, err := csvOptions(*preset, *columns, *delim, *detail, len(opts.TieBreakers) > 0)
+	if err != nil {
+		fatal(err)
+	}
+
+	standings, err := newStandings(s, opts)
+	if err != nil {
+		fatal(err)
+	}
+	prev := snapshots.latest()
+	snapshots.save(standings)
+	hooks.notify(prev, standings)
+	printOOM(standings, *s.out, csvOpts)
+	if *xlsx != "" {
+		writeFile(*xlsx, standings.WriteXLSX)
+	}
+}
+
+// csvOptions builds the csv layout from the preset, optionally overriding
+// its columns and delimiter.  The preset columns include the tie-breaker
+// if tieBreak is true
+func csvOptions(preset string, columns string, delim string, detail bool, tieBreak bool) (oom.CSVOptions, error) {
+	opts := oom.ClassicCSV(detail)
+	switch preset {
+	case "classic":
+	case "flat":
+		opts.Classic = false
+	default:
+		return opts, errors.New("unknown preset " + preset)
+	}
+	if columns != "" {
+		cols, err := oom.ParseColumns(columns)
+		if err != nil {
+			return opts, err
+		}
+		opts.Columns = cols
+	} else if tieBreak {
+		opts.Columns = append(opts.Columns, oom.ColTieBreak)
+	}
+	if delim == "tab" || delim == "\\t" {
+		delim = "\t"
+	}
+	if utf8.RuneCountInString(delim) != 1 {
+		return opts, errors.New("delimiter must be a single character")
+	}
+	opts.Comma, _ = utf8.DecodeRuneInString(delim)
+	return opts, nil
+}
+
+func printOOM(standings *oom.Standings, fname string, opts oom.CSVOptions) {
+	writeFile(fname, func(w io.Writer) error {
+		return standings.WriteCSVWith(w, opts)
+	})
+}
@@ -0,0 +1,40 @@
+package main
+
+// creds.go has the creds command, saving the credentials encrypted
+
+import (
+	"errors"
+	"matt/oom"
+)
+
+// creds prompts for the email, pin and a passphrase and saves the email and
+// pin in --creds-enc encrypted with the passphrase, for login to unlock
+func creds(args []string) {
+	fs, s := newCommand("creds", "", "", "")
+	fs.Parse(args)
+	s.apply()
+	email, err := oom.ReadLine("Email: ")
+	if err != nil {
+		fatal(err)
+	}
+	pin, err := oom.ReadSecret("PIN: ")
+	if err != nil {
+		fatal(err)
+	}
+	passphrase, err := oom.ReadSecret("Passphrase: ")
+	if err != nil {
+		fatal(err)
+	}
+	again, err := oom.ReadSecret("Passphrase again: ")
+	if err != nil {
+		fatal(err)
+	}
+	if passphrase != again {
+		fatal(errors.New("the passphrases differ"))
+	}
+	if err := oom.SaveEncryptedCreds(*s.credsEnc, passphrase, email, pin); err != nil {
+		fatal(err)
+	}
+	oom.Logger.Info("saved the credentials, remove the plain text file if you had one",
+		"file", *s.credsEnc, "plain", *s.creds)
+}
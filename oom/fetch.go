@@ -0,0 +1,163 @@
+package main
+
+// fetch.go has the commands that read the website: list and fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"matt/oom"
+	"os"
+	"os/signal"
+	"sort"
+	"text/tabwriter"
+)
+
+// list writes the year's competitions on the website, noting those in the
+// config file and those cached
+func list(args []string) {
+	fs, s := newCommand("list", "", "-", "file to write the list to, - for stdout")
+	fs.Parse(args)
+	s.apply()
+	comps, err := s.client.FetchAllCompDesc(*s.year)
+	if err != nil {
+		fatal(err)
+	}
+	sort.SliceStable(comps, func(i, j int) bool {
+		return comps[i].Time().Before(comps[j].Time())
+	})
+	inConfig := make(map[string]oom.Competition)
+	if _, err := os.Stat(*s.config); err == nil {
+		keys, err := oom.CompKeys(*s.config)
+		if err != nil {
+			fatal(err)
+		}
+		for _, comp := range keys {
+			inConfig[comp.Key] = comp
+		}
+	}
+	cached, err := cachedKeys(s)
+	if err != nil {
+		fatal(err)
+	}
+	writeFile(*s.out, func(w io.Writer) error {
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "Key\tDate\tName\tIn config\tCached")
+		for _, comp := range comps {
+			conf := ""
+			if c, ok := inConfig[comp.Key]; ok {
+				conf = "yes"
+				if c.Major {
+					conf = "major"
+				}
+			}
+			isCached := ""
+			if cached[comp.Key] {
+				isCached = "yes"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", comp.Key, comp.Date, comp.Name, conf, isCached)
+		}
+		return tw.Flush()
+	})
+}
+
+// fetch loads the competitions in to the cache, listing them
+func fetch(args []string) {
+	fs, s := newCommand("fetch", "", "-", "file to list the fetched competitions in, - for stdout")
+	fs.Parse(args)
+	s.apply()
+	comps, err := competitions(s)
+	if err != nil {
+		fatal(err)
+	}
+	if err := loadAll(s, comps); err != nil {
+		fatal(err)
+	}
+	writeFile(*s.out, func(w io.Writer) error {
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "Key\tDate\tName\tPlayers")
+		for _, comp := range comps {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", comp.Key, comp.Date, comp.Name, comp.NumPlayers)
+		}
+		return tw.Flush()
+	})
+}
+
+// competitions returns the competitions listed in the config file, or all
+// the year's, with their descriptions fetched if need be.  Offline with
+// no cached list of the year's competitions, all of them are those cached
+func competitions(s *settings) ([]oom.Competition, error) {
+	if !*s.all {
+		return s.client.FetchCompDescriptions(*s.year, *s.config)
+	}
+	comps, err := s.client.FetchAllCompDesc(*s.year)
+	if err != nil || len(comps) > 0 || !*s.offline {
+		return comps, err
+	}
+	oom.Logger.Warn("no cached list of competitions, using the cached competitions", "year", *s.year)
+	files, err := s.client.ReadCache()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if f.Comp != nil && f.Comp.Time().Year() == *s.year {
+			comps = append(comps, *f.Comp)
+		}
+	}
+	return comps, nil
+}
+
+// newStandings loads the competitions and returns their standings, noting
+// any competitions missing
+func newStandings(s *settings, opts oom.Options) (*oom.Standings, error) {
+	comps, err := competitions(s)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadAll(s, comps); err != nil {
+		return nil, err
+	}
+	standings := oom.NewStandings(*s.year, comps, opts)
+	if incomplete := standings.Incomplete(); incomplete != "" {
+		oom.Logger.Warn(incomplete)
+	}
+	return standings, nil
+}
+
+// cachedKeys returns the keys of the cached competitions
+func cachedKeys(s *settings) (map[string]bool, error) {
+	files, err := s.client.ReadCache()
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]bool)
+	for _, f := range files {
+		if f.Comp != nil {
+			ret[f.Comp.Key] = true
+		}
+	}
+	return ret, nil
+}
+
+// loadAll loads the competitions, fetching those not cached unless
+// offline, --jobs at a time.  Any that can't be loaded are left for
+// NewStandings to list as missing.  An interrupt abandons the loading,
+// returning an error
+func loadAll(s *settings, competitions []oom.Competition) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	p := newProgress(s)
+	s.client.LoadAll(ctx, competitions, oom.LoadOptions{Concurrency: *s.jobs,
+		Progress: func(done, total int, comp *oom.Competition, err error) {
+			if err != nil && ctx.Err() == nil {
+				oom.Logger.Warn("loading competition", "comp", comp.Title(), "err", err)
+			}
+			p.update(done, total, comp)
+		}})
+	p.clear()
+	if ctx.Err() != nil {
+		return errors.New("interrupted")
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package main
+
+// flags.go defines the flags shared by the commands
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"matt/oom"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// settings are the flags every command takes
+type settings struct {
+	config   *string
+	cacheDir *string
+	creds    *string
+	session  *string
+	netrc    *string
+	credsEnc *string
+	timeout  *time.Duration
+	retries  *int
+	backoff  *time.Duration
+	spacing  *time.Duration
+	agent    *string
+	jobs     *int
+	verbose  *bool
+	quiet    *bool
+	logJSON  *bool
+	out      *string
+	year     *int
+	all      *bool
+	offline  *bool
+
+	client *oom.Client // made from the flags by apply
+}
+
+// newCommand returns the flag set for the command taking args, with the
+// settings flags.  out is the default for --out, described by outUsage,
+// which is left out if outUsage is empty
+func newCommand(name string, args string, out string, outUsage string) (*flag.FlagSet, *settings) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), strings.Join(strings.Fields("usage: oom "+name+" [flags] "+args), " "))
+		fs.PrintDefaults()
+	}
+	defaults := oom.NewClient()
+	s := &settings{
+		config:   fs.String("config", "oom.conf", "file listing the competitions in the order of merit"),
+		cacheDir: fs.String("cache-dir", ".", "directory the competition results and lists are cached in"),
+		creds:    fs.String("creds", defaults.CredsFile, "file with the email and pin to log in with, otherwise prompted for"),
+		netrc:    fs.String("netrc", defaults.NetrcFile, "netrc file with the email and pin as login and password"),
+		credsEnc: fs.String("creds-enc", defaults.EncryptedCredsFile, "file with the email and pin encrypted by oom creds"),
+		timeout:  fs.Duration("timeout", defaults.Timeout, "time limit on each request to the website"),
+		retries:  fs.Int("retries", defaults.Retries, "times to retry requests failing with timeouts or server errors, the delay doubling"),
+		backoff:  fs.Duration("backoff", defaults.Backoff, "delay before the first retry of a failed request"),
+		spacing:  fs.Duration("request-interval", defaults.RequestInterval, "least time between requests to the website"),
+		agent:    fs.String("user-agent", defaults.UserAgent, "User-Agent sent to the website"),
+		verbose:  fs.Bool("v", false, "log the pages fetched and more"),
+		quiet:    fs.Bool("q", false, "log only warnings and errors, with no progress display"),
+		logJSON:  fs.Bool("log-json", false, "log as json lines"),
+		jobs:     fs.Int("jobs", 10, "competitions to load at once"),
+		session:  fs.String("session", defaults.SessionFile, "file the website login is kept in between runs, empty for none"),
+		year:     fs.Int("year", 0, "default to current year"),
+		all:      fs.Bool("all", false, "true for all comps"),
+		offline:  fs.Bool("offline", false, "never fetch from the website, using only the cached files"),
+	}
+	s.out = &out
+	if outUsage != "" {
+		s.out = fs.String("out", out, outUsage)
+	}
+	return fs, s
+}
+
+// parseArgs parses args into fs and returns the positional arguments,
+// allowing flags after and between them as well as before, up to any "--"
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var words []string
+	fs.Parse(args)
+	for fs.NArg() > 0 {
+		rest := fs.Args()
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			return append(words, rest...)
+		}
+		words = append(words, rest[0])
+		args = rest[1:]
+		fs.Parse(args)
+	}
+	return words
+}
+
+// apply sets up the logging and the client from the settings, once parsed
+func (s *settings) apply() {
+	level := slog.LevelInfo
+	if *s.verbose {
+		level = slog.LevelDebug
+	} else if *s.quiet {
+		level = slog.LevelWarn
+	}
+	if *s.logJSON {
+		oom.Logger = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	} else {
+		slog.SetLogLoggerLevel(level)
+		log.SetOutput(stderr)
+	}
+	oom.Logger.Debug("running ladies version...")
+	s.client = oom.NewClient()
+	s.client.CacheDir = *s.cacheDir
+	s.client.CredsFile = *s.creds
+	s.client.SessionFile = *s.session
+	s.client.NetrcFile = *s.netrc
+	s.client.EncryptedCredsFile = *s.credsEnc
+	s.client.Timeout = *s.timeout
+	s.client.Retries = *s.retries
+	s.client.Backoff = *s.backoff
+	s.client.RequestInterval = *s.spacing
+	s.client.UserAgent = *s.agent
+	s.client.Offline = *s.offline
+	if *s.year == 0 {
+		*s.year = time.Now().Year()
+	}
+}
+
+// rankFlags are the flags controlling how the standings are calculated
+type rankFlags struct {
+	maxComps  *int
+	tieBreak  *string
+	lastN     *int
+	minComps  *int
+	padToMin  *bool
+	period    *string
+	perPeriod *int
+	minMajors *int
+}
+
+func addRankFlags(fs *flag.FlagSet) *rankFlags {
+	return &rankFlags{
+		maxComps:  fs.Int("maxComps", 10, "Best (10) Competition scores to count"),
+		tieBreak:  fs.String("tiebreak", "", "ordered tie-breakers from played,best,wins,lastN,h2h (default ties share a rank)"),
+		lastN:     fs.Int("lastN", 3, "number of competitions counted by the lastN tie-breaker"),
+		minComps:  fs.Int("minComps", 0, "competitions needed to qualify, others are provisional"),
+		padToMin:  fs.Bool("padToMin", false, "rank provisional players in the main table, zero padded to minComps"),
+		period:    fs.String("period", "none", "month, quarter or half for perPeriod"),
+		perPeriod: fs.Int("perPeriod", 0, "best scores to count in each period, 0 for no limit"),
+		minMajors: fs.Int("minMajors", 0, "best scores from majors in oom.conf that always count"),
+	}
+}
+
+// options returns the standings options, or dies if a flag is invalid
+func (f *rankFlags) options() oom.Options {
+	period, err := oom.ParsePeriod(*f.period)
+	if err != nil {
+		fatal(err)
+	}
+	tieBreakers, err := oom.ParseTieBreakers(*f.tieBreak)
+	if err != nil {
+		fatal(err)
+	}
+	return oom.Options{MaxComps: *f.maxComps, TieBreakers: tieBreakers,
+		LastN: *f.lastN, MinComps: *f.minComps, PadToMin: *f.padToMin,
+		Period: period, PerPeriod: *f.perPeriod, MinMajors: *f.minMajors}
+}
+
+// snapshotFlags are the flags for the snapshots of the standings kept
+// between runs, see oom.Diff
+type snapshotFlags struct {
+	fs       *flag.FlagSet
+	cacheDir *string
+	dir      *string
+	keep     *int
+}
+
+func addSnapshotFlags(fs *flag.FlagSet, s *settings) *snapshotFlags {
+	return &snapshotFlags{
+		fs:       fs,
+		cacheDir: s.cacheDir,
+		dir:      addSnapshotsFlag(fs, "directory to keep a json snapshot of each run's standings in, empty for none"),
+		keep:     fs.Int("keepSnapshots", 20, "snapshots to keep, the oldest being removed, 0 to keep them all"),
+	}
+}
+
+// addSnapshotsFlag adds --snapshots, resolved by snapshotsDir
+func addSnapshotsFlag(fs *flag.FlagSet, usage string) *string {
+	return fs.String("snapshots", "", usage+" (default snapshots in --cache-dir)")
+}
+
+// snapshotsDir returns --snapshots as given, or if not given the snapshots
+// directory in the cache directory
+func snapshotsDir(fs *flag.FlagSet, dir *string, cacheDir string) string {
+	given := false
+	fs.Visit(func(f *flag.Flag) {
+		given = given || f.Name == "snapshots"
+	})
+	if !given {
+		return filepath.Join(cacheDir, "snapshots")
+	}
+	return *dir
+}
+
+// save saves the standings, unless --snapshots is empty, removing the
+// oldest snapshots beyond --keepSnapshots
+func (f *snapshotFlags) save(standings *oom.Standings) {
+	dir := snapshotsDir(f.fs, f.dir, *f.cacheDir)
+	if dir == "" {
+		return
+	}
+	fname, err := standings.SaveSnapshot(dir)
+	if err != nil {
+		fatal(err)
+	}
+	oom.Logger.Info("saved snapshot", "file", fname)
+	if *f.keep <= 0 {
+		return
+	}
+	removed, err := oom.PruneSnapshots(dir, *f.keep)
+	if err != nil {
+		oom.Logger.Warn("removing old snapshots", "err", err)
+	}
+	for _, fname := range removed {
+		oom.Logger.Debug("removed old snapshot", "file", fname)
+	}
+}
+
+// latest returns the standings last saved, or nil if there are none
+func (f *snapshotFlags) latest() *oom.Standings {
+	dir := snapshotsDir(f.fs, f.dir, *f.cacheDir)
+	if dir == "" {
+		return nil
+	}
+	fnames, err := oom.LatestSnapshots(dir, 1)
+	if err != nil || len(fnames) == 0 {
+		return nil
+	}
+	doc, err := oom.ReadSnapshot(fnames[0])
+	if err != nil {
+		oom.Logger.Warn("reading snapshot", "err", err)
+		return nil
+	}
+	return doc.Standings
+}
+
+// hookList is the repeatable --hook flag
+type hookList []string
+
+func (h *hookList) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *hookList) Set(target string) error {
+	*h = append(*h, target)
+	return nil
+}
+
+// hookFlags are the flags configuring the hooks notified of changes in
+// the standings, see oom.Hooks
+type hookFlags struct {
+	targets hookList
+	top     *int
+	retries *int
+	backoff *time.Duration
+	dryRun  *bool
+}
+
+func addHookFlags(fs *flag.FlagSet) *hookFlags {
+	h := &hookFlags{}
+	fs.Var(&h.targets, "hook", "URL to POST changes in the standings to as json, or command to run with them on stdin, repeatable")
+	h.top = fs.Int("hookTop", 10, "places at the top of the standings whose changes are notified")
+	h.retries = fs.Int("hookRetries", 3, "times a failed hook is retried")
+	h.backoff = fs.Duration("hookBackoff", 5*time.Second, "delay before retrying a failed hook, doubling each retry")
+	h.dryRun = fs.Bool("hookDryRun", false, "show what the hooks would be sent rather than sending it")
+	return h
+}
+
+// notify runs the hooks with the changes from prev to standings, if any
+func (h *hookFlags) notify(prev *oom.Standings, standings *oom.Standings) {
+	if len(h.targets) == 0 {
+		return
+	}
+	if prev == nil {
+		oom.Logger.Info("hooks: no previous standings to compare with")
+		return
+	}
+	n := oom.NewNotice(prev, standings, *h.top)
+	if n.Empty() {
+		oom.Logger.Info("hooks: no changes to notify")
+		return
+	}
+	hooks := oom.Hooks{Targets: h.targets, Retries: *h.retries, Backoff: *h.backoff,
+		DryRun: *h.dryRun}
+	if err := hooks.Notify(n); err != nil {
+		oom.Logger.Warn("hooks", "err", err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+// progress.go shows the progress of loading the competitions on the
+// terminal, a line rewritten as each competition loads
+
+import (
+	"fmt"
+	"matt/oom"
+	"os"
+	"sync"
+)
+
+// progress is the line on the terminal, off when stderr isn't a terminal,
+// e.g. in a scheduled run, or when logging quietly or as json
+type progress struct {
+	on bool
+}
+
+func newProgress(s *settings) *progress {
+	fi, err := os.Stderr.Stat()
+	tty := err == nil && fi.Mode()&os.ModeCharDevice != 0
+	return &progress{on: tty && !*s.quiet && !*s.logJSON}
+}
+
+// update shows that done of total competitions are loaded, comp the last
+func (p *progress) update(done int, total int, comp *oom.Competition) {
+	if !p.on {
+		return
+	}
+	stderr.show(fmt.Sprintf("loaded %d/%d competitions: %.50s", done, total, comp.Title()))
+}
+
+// clear removes the line once done
+func (p *progress) clear() {
+	stderr.mutex.Lock()
+	defer stderr.mutex.Unlock()
+	stderr.clear()
+}
+
+// stderr is where the log is written, see settings.apply, clearing the
+// progress line before each record so the two don't run together
+var stderr = &terminal{}
+
+// terminal is os.Stderr with a progress line shown on it
+type terminal struct {
+	mutex sync.Mutex
+	shown bool // the progress line is on the terminal
+}
+
+func (t *terminal) Write(b []byte) (int, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.clear()
+	return os.Stderr.Write(b)
+}
+
+// show replaces the progress line with line
+func (t *terminal) show(line string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	fmt.Fprint(os.Stderr, "\r\033[K", line)
+	t.shown = true
+}
+
+// clear removes the progress line, t.mutex held
+func (t *terminal) clear() {
+	if t.shown {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+		t.shown = false
+	}
+}
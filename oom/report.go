@@ -0,0 +1,149 @@
+package main
+
+// report.go has the commands that render the standings from the cache:
+// report, player and diff
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"matt/oom"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// report writes the standings in each of the formats asked for
+func report(args []string) {
+	fs, s := newCommand("report", "", ".", "directory the report files are written to")
+	rank := addRankFlags(fs)
+	html := fs.String("html", "", "directory to write the static html site to")
+	templates := fs.String("templates", "", "directory of html templates overriding the defaults")
+	jsonFile := fs.String("json", "", "file to write the standings to as json")
+	ndjson := fs.String("ndjson", "", "file to write a json result row per line to")
+	text := fs.String("text", "", "file to write a plain text leaderboard to, - for stdout")
+	markdown := fs.String("markdown", "", "file to write a markdown leaderboard to, - for stdout")
+	top := fs.Int("top", 0, "only show the top players in text and markdown, 0 for all")
+	charts := fs.String("charts", "", "directory to write rank.svg, points.svg and field.svg charts to")
+	chartTop := fs.Int("chartTop", 10, "players drawn on the rank and points charts")
+	snapshots := addSnapshotFlags(fs, s)
+	hooks := addHookFlags(fs)
+	fs.Parse(args)
+	*s.offline = true
+	s.apply()
+	if *html == "" && *jsonFile == "" && *ndjson == "" && *text == "" &&
+		*markdown == "" && *charts == "" {
+		fatal(errors.New("report: nothing to do, use --html or --charts DIR, --json, --ndjson, --text or --markdown FILE"))
+	}
+	standings, err := newStandings(s, rank.options())
+	if err != nil {
+		fatal(err)
+	}
+	prev := snapshots.latest()
+	snapshots.save(standings)
+	hooks.notify(prev, standings)
+
+	if err := os.MkdirAll(*s.out, 0755); err != nil {
+		fatal(err)
+	}
+	// path returns fname in the --out directory
+	path := func(fname string) string {
+		if fname == "-" || filepath.IsAbs(fname) {
+			return fname
+		}
+		return filepath.Join(*s.out, fname)
+	}
+	if *html != "" {
+		if err := standings.WriteHTML(path(*html), *templates); err != nil {
+			fatal(err)
+		}
+	}
+	if *jsonFile != "" {
+		writeFile(path(*jsonFile), standings.WriteJSON)
+	}
+	if *ndjson != "" {
+		writeFile(path(*ndjson), standings.WriteNDJSON)
+	}
+	if *text != "" {
+		writeFile(path(*text), func(w io.Writer) error {
+			return standings.WriteText(w, oom.TextOptions{Top: *top})
+		})
+	}
+	if *markdown != "" {
+		writeFile(path(*markdown), func(w io.Writer) error {
+			return standings.WriteText(w, oom.TextOptions{Markdown: true, Top: *top})
+		})
+	}
+	if *charts != "" {
+		dir := path(*charts)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fatal(err)
+		}
+		opts := oom.ChartOptions{Top: *chartTop}
+		for fname, write := range map[string]func(io.Writer, oom.ChartOptions) error{
+			"rank.svg": standings.WriteRankChart, "points.svg": standings.WritePointsChart,
+			"field.svg": standings.WriteFieldChart} {
+			writeFile(filepath.Join(dir, fname), func(w io.Writer) error {
+				return write(w, opts)
+			})
+		}
+	}
+}
+
+// player writes the named player's statement, explaining their standing
+func player(args []string) {
+	fs, s := newCommand("player", "NAME", "-", "file to write the statement to, .html for a web page, - for stdout")
+	rank := addRankFlags(fs)
+	templates := fs.String("templates", "", "directory of html templates overriding the defaults")
+	name := parseArgs(fs, args)
+	*s.offline = true
+	s.apply()
+	if len(name) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	standings, err := newStandings(s, rank.options())
+	if err != nil {
+		fatal(err)
+	}
+	st, err := standings.Statement(strings.Join(name, " "))
+	if err != nil {
+		fatal(err)
+	}
+	if strings.HasSuffix(*s.out, ".html") {
+		writeFile(*s.out, func(w io.Writer) error {
+			return st.WriteHTML(w, *templates)
+		})
+	} else {
+		writeFile(*s.out, st.WriteText)
+	}
+}
+
+// diff writes the changes between two snapshots, by default the latest two
+func diff(args []string) {
+	fs, s := newCommand("diff", "[OLD.json NEW.json]", "-", "file to write the changes to, - for stdout")
+	snapshots := addSnapshotsFlag(fs, "directory of the snapshots to compare")
+	fnames := parseArgs(fs, args)
+	s.apply()
+	if len(fnames) == 0 {
+		var err error
+		if fnames, err = oom.LatestSnapshots(snapshotsDir(fs, snapshots, *s.cacheDir), 2); err != nil {
+			fatal(err)
+		}
+	}
+	if len(fnames) != 2 {
+		fatal(errors.New("diff: need two snapshots to compare"))
+	}
+	old, err := oom.ReadSnapshot(fnames[0])
+	if err != nil {
+		fatal(err)
+	}
+	cur, err := oom.ReadSnapshot(fnames[1])
+	if err != nil {
+		fatal(err)
+	}
+	writeFile(*s.out, func(w io.Writer) error {
+		fmt.Fprintf(w, "Changes from %s to %s\n", fnames[0], fnames[1])
+		return oom.Diff(old.Standings, cur.Standings).WriteText(w)
+	})
+}
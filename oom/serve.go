@@ -0,0 +1,25 @@
+package main
+
+// serve.go has the serve command, running the standings as a web service
+
+import (
+	"matt/oom"
+	"net/http"
+)
+
+// serve serves the standings computed from the cache, see oom.Server
+func serve(args []string) {
+	fs, s := newCommand("serve", "", "", "")
+	rank := addRankFlags(fs)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	templates := fs.String("templates", "", "directory of html templates overriding the defaults")
+	fs.Parse(args)
+	*s.offline = true
+	s.apply()
+	opts := rank.options()
+	srv := oom.NewServer(func() (*oom.Standings, error) {
+		return newStandings(s, opts)
+	}, *s.cacheDir, *templates)
+	oom.Logger.Info("serving the standings", "addr", *addr)
+	fatal(http.ListenAndServe(*addr, srv))
+}
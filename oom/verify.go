@@ -0,0 +1,70 @@
+package main
+
+// verify.go has the verify command, checking the cache against the website
+
+import (
+	"context"
+	"errors"
+	"io"
+	"matt/oom"
+	"os"
+	"os/signal"
+)
+
+// verify refetches the cached competitions, or those with the keys given,
+// and writes where the results on the website differ from the cache,
+// updating the cache with --apply
+func verify(args []string) {
+	fs, s := newCommand("verify", "[KEY...]", "-", "file to write the differences to, - for stdout")
+	apply := fs.Bool("apply", false, "update the cache with the results on the website, keeping the overrides")
+	keys := parseArgs(fs, args)
+	s.apply()
+	var comps []oom.Competition
+	for _, key := range keys {
+		comps = append(comps, oom.Competition{Key: key})
+	}
+	if len(comps) == 0 {
+		files, err := s.client.ReadCache()
+		if err != nil {
+			fatal(err)
+		}
+		for _, f := range files {
+			if f.Comp != nil {
+				comps = append(comps, *f.Comp)
+			}
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	var vs []*oom.Verification
+	for _, comp := range comps {
+		v, err := s.client.Verify(ctx, comp)
+		if ctx.Err() != nil {
+			fatal(errors.New("interrupted"))
+		}
+		if err != nil {
+			oom.Logger.Warn("verifying competition", "key", comp.Key, "err", err)
+			continue
+		}
+		vs = append(vs, v)
+	}
+	writeFile(*s.out, func(w io.Writer) error {
+		for _, v := range vs {
+			if err := v.WriteText(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if !*apply {
+		return
+	}
+	for _, v := range vs {
+		if v.Changed() {
+			if err := v.Apply(); err != nil {
+				fatal(err)
+			}
+			oom.Logger.Info("updated the cache", "key", v.Cached.Key, "comp", v.Cached.Name)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+// watch.go has the watch command, polling the website for new results
+
+import (
+	"matt/oom"
+	"time"
+)
+
+// watch polls for competitions in the series as their results are
+// published, recomputing the standings and notifying the hooks of the
+// changes
+func watch(args []string) {
+	fs, s := newCommand("watch", "", "-", "file to write the changes in the standings to, - for stdout")
+	rank := addRankFlags(fs)
+	interval := fs.Duration("interval", 30*time.Minute, "time between polls")
+	once := fs.Bool("once", false, "poll once and exit, e.g. when run from cron")
+	snapshots := addSnapshotFlags(fs, s)
+	hooks := addHookFlags(fs)
+	fs.Parse(args)
+	s.apply()
+	opts := rank.options()
+	w := oom.Watcher{Client: s.client, Year: *s.year, Config: *s.config, All: *s.all}
+	prev, err := newStandings(s, opts)
+	if err != nil {
+		fatal(err)
+	}
+	stale := false // new results are cached but the standings failed to load
+	for {
+		comps, err := w.Poll()
+		if err != nil && *once {
+			fatal(err)
+		} else if err != nil {
+			oom.Logger.Error("polling, will poll again", "err", err, "interval", *interval)
+		}
+		if len(comps) > 0 || stale {
+			standings, err := newStandings(s, opts)
+			if err != nil && *once {
+				fatal(err)
+			}
+			stale = err != nil
+			if err != nil {
+				oom.Logger.Error("loading the standings, will poll again", "err", err, "interval", *interval)
+			} else {
+				snapshots.save(standings)
+				d := oom.Diff(prev, standings)
+				writeFile(*s.out, d.WriteText)
+				hooks.notify(prev, standings)
+				prev = standings
+			}
+		}
+		if *once {
+			return
+		}
+		time.Sleep(*interval)
+	}
+}
@@ -0,0 +1,155 @@
+package oom
+
+// pages.go keeps the pages fetched from the website in CacheDir/pages,
+// with their ETag, Last-Modified and a hash of their content in a json
+// file beside each, both named by a hash of the URL.  A kept page is
+// fetched with a conditional GET, so it is only downloaded again if it has
+// changed, and the hash tells when a page, say a competition's results,
+// changed after it was first fetched.  The pages are only readable by
+// their owner, as the results are behind the website's login
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// PageInfo describes a page kept from the website
+type PageInfo struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	Hash         string    `json:"hash"` // sha256 of the content, in hex
+	File         string    `json:"file"` // the content, in CacheDir/pages, beside FILE.json
+	Fetched      time.Time `json:"fetched"`
+	Changed      time.Time `json:"changed"` // when the content was first seen
+	Versions     int       `json:"versions"`
+}
+
+// Amended returns true if the page has changed since it was first fetched
+func (p PageInfo) Amended() bool {
+	return p.Versions > 1
+}
+
+const pagesDir = "pages"
+
+// pageName returns the name of the files the page from urlString is kept
+// in, without their extension
+func pageName(urlString string) string {
+	return hash([]byte(urlString))[:16]
+}
+
+// pagePaths returns the paths of the files the page from urlString is
+// kept in: its description and its content
+func (c *Client) pagePaths(urlString string) []string {
+	name := c.cachePath(filepath.Join(pagesDir, pageName(urlString)))
+	return []string{name + ".json", name + ".html"}
+}
+
+func (c *Client) readPage(urlString string) (PageInfo, bool) {
+	var p PageInfo
+	data, err := ioutil.ReadFile(c.pagePaths(urlString)[0])
+	if err != nil {
+		return p, false
+	}
+	if err := json.Unmarshal(data, &p); err != nil || p.URL != urlString {
+		Logger.Warn("ignoring the description of a page kept", "url", urlString, "err", err)
+		return PageInfo{}, false
+	}
+	return p, true
+}
+
+func (c *Client) writePage(p PageInfo) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.pagePaths(p.URL)[0], data, 0600)
+}
+
+// Page returns what is known of the page kept from urlString, false if
+// it isn't kept
+func (c *Client) Page(urlString string) (PageInfo, bool) {
+	c.pagesMutex.Lock()
+	defer c.pagesMutex.Unlock()
+	return c.readPage(urlString)
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// keptPage returns the content kept from urlString and the header making
+// the GET of it conditional, or nil if it isn't kept or its content has
+// been changed since, e.g. removed or edited
+func (c *Client) keptPage(urlString string) ([]byte, http.Header) {
+	c.pagesMutex.Lock()
+	defer c.pagesMutex.Unlock()
+	p, ok := c.readPage(urlString)
+	if !ok || (p.ETag == "" && p.LastModified == "") {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(c.cachePath(filepath.Join(pagesDir, p.File)))
+	if err != nil || hash(data) != p.Hash {
+		return nil, nil
+	}
+	header := make(http.Header)
+	if p.ETag != "" {
+		header.Set("If-None-Match", p.ETag)
+	}
+	if p.LastModified != "" {
+		header.Set("If-Modified-Since", p.LastModified)
+	}
+	return data, header
+}
+
+// keepPage keeps the content of a 200 response to the GET of urlString,
+// noting if it has changed
+func (c *Client) keepPage(urlString string, resp response) {
+	c.pagesMutex.Lock()
+	defer c.pagesMutex.Unlock()
+	old, ok := c.readPage(urlString)
+	now := time.Now().UTC()
+	p := PageInfo{URL: urlString, ETag: resp.header.Get("ETag"),
+		LastModified: resp.header.Get("Last-Modified"), Hash: hash(resp.body),
+		File: pageName(urlString) + ".html", Fetched: now,
+		Changed: old.Changed, Versions: old.Versions}
+	if !ok || old.Hash != p.Hash {
+		p.Changed = now
+		p.Versions++
+		if ok {
+			Logger.Info("page changed since last fetched", "url", urlString,
+				"last", old.Changed.Format(time.RFC3339))
+		}
+	}
+	if err := os.MkdirAll(c.cachePath(pagesDir), 0700); err != nil {
+		Logger.Warn("keeping page", "url", urlString, "err", err)
+		return
+	}
+	err := ioutil.WriteFile(c.cachePath(filepath.Join(pagesDir, p.File)), resp.body, 0600)
+	if err == nil {
+		err = c.writePage(p)
+	}
+	if err != nil {
+		Logger.Warn("keeping page", "url", urlString, "err", err)
+	}
+}
+
+// pageNotModified notes that the page kept from urlString was found
+// unchanged
+func (c *Client) pageNotModified(urlString string) {
+	c.pagesMutex.Lock()
+	defer c.pagesMutex.Unlock()
+	if p, ok := c.readPage(urlString); ok {
+		p.Fetched = time.Now().UTC()
+		if err := c.writePage(p); err != nil {
+			Logger.Warn("keeping page", "url", urlString, "err", err)
+		}
+	}
+}
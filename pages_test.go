@@ -0,0 +1,84 @@
+package oom
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConditionalGet(t *testing.T) {
+	c := newTestClient(t)
+	c.Retries = 0
+	content := "results v1"
+	var downloads, conditional int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := fmt.Sprintf(`"%d"`, len(content)) // differs for each version
+		if r.URL.Path == "/modified" {
+			w.Header().Set("Last-Modified", "Fri, 25 Mar 2016 18:00:00 GMT")
+			if len(content) > len("results v1") {
+				w.Header().Set("Last-Modified", "Sat, 26 Mar 2016 09:00:00 GMT")
+			}
+		} else {
+			w.Header().Set("ETag", etag)
+		}
+		if r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+			conditional++
+		}
+		if r.Header.Get("If-None-Match") == etag && r.URL.Path == "/etag" ||
+			r.Header.Get("If-Modified-Since") == w.Header().Get("Last-Modified") && r.URL.Path == "/modified" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		fmt.Fprint(w, content)
+	}))
+	defer ts.Close()
+
+	for _, path := range []string{"/etag", "/modified"} {
+		content = "results v1"
+		downloads, conditional = 0, 0
+		page := ts.URL + path
+		get := func(want string) {
+			data, err := c.fetch(context.Background(), page)
+			if err != nil || string(data) != want {
+				t.Errorf("%s: expected %q, got %q %v", path, want, data, err)
+			}
+		}
+		get("results v1")
+		get("results v1")
+		if downloads != 1 || conditional != 1 {
+			t.Errorf("%s: expected 1 download and 1 conditional GET, got %d and %d", path, downloads, conditional)
+		}
+		p, ok := c.Page(page)
+		if !ok || p.Amended() || p.Hash != hash([]byte("results v1")) {
+			t.Errorf("%s: expected the page kept unamended, got %v", path, p)
+		}
+		for _, fname := range c.pagePaths(page) {
+			if fi, err := os.Stat(fname); err != nil || fi.Mode().Perm() != 0600 {
+				t.Errorf("%s: expected %s kept with mode 0600, got %v", path, fname, err)
+			}
+		}
+
+		content = "results v1, amended"
+		get("results v1, amended")
+		get("results v1, amended")
+		if downloads != 2 {
+			t.Errorf("%s: expected 2 downloads, got %d", path, downloads)
+		}
+		if p, _ := c.Page(page); !p.Amended() || p.Versions != 2 || p.Hash != hash([]byte(content)) {
+			t.Errorf("%s: expected the page amended, got %v", path, p)
+		}
+
+		// a kept page that's been tampered with is downloaded again
+		p, _ = c.Page(page)
+		os.WriteFile(filepath.Join(c.CacheDir, pagesDir, p.File), []byte("edited"), 0644)
+		get("results v1, amended")
+		if downloads != 3 {
+			t.Errorf("%s: expected 3 downloads, got %d", path, downloads)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package oom
+
+// server.go serves the standings over http, so the club website can embed
+// the order of merit rather than having a spreadsheet uploaded:
+// - /api/series: the season, its options and competitions
+// - /api/standings: the standings as the json Document, see json.go
+// - /api/players/NAME: a player's statement, NAME or its slug e.g. jo-mager
+// - /api/competitions/KEY: a competition's results
+// - anything else: the pages of the html site, see html.go
+// The standings are recomputed when the files in the cache change, so a
+// newly fetched competition appears without restarting the server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Server is an http.Handler serving the standings
+type Server struct {
+	load        func() (*Standings, error) // computes the standings from the cache
+	cacheDir    string
+	templateDir string
+
+	mutex     sync.Mutex
+	version   string // of the cache the standings were computed from
+	standings *Standings
+	pages     map[string]sitePage
+	templates *template.Template
+}
+
+// NewServer returns a server for the standings computed by load from the
+// files cached in cacheDir, whose html pages use the templates in
+// templateDir, which may be empty to use the defaults.  An error from load
+// is served as a 500 Internal Server Error, load being tried again by the
+// next request
+func NewServer(load func() (*Standings, error), cacheDir string, templateDir string) *Server {
+	return &Server{load: load, cacheDir: cacheDir, templateDir: templateDir}
+}
+
+// cacheVersion returns a string that changes whenever a file in dir is
+// added, removed or modified
+func cacheVersion(dir string) string {
+	if dir == "" {
+		dir = "."
+	}
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err.Error()
+	}
+	var b bytes.Buffer
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			fmt.Fprintf(&b, "%s %d %d\n", fi.Name(), fi.Size(), fi.ModTime().UnixNano())
+		}
+	}
+	return b.String()
+}
+
+// current returns the standings and site, recomputed if the cache has
+// changed since they were last computed
+func (srv *Server) current() (*Standings, map[string]sitePage, *template.Template, error) {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+	if version := cacheVersion(srv.cacheDir); srv.standings == nil || version != srv.version {
+		t, err := htmlTemplates(srv.templateDir)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		s, err := srv.load()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		pages, err := s.site()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		Logger.Info("computed standings", "competitions", len(s.Competitions), "players", len(s.RankedPlayers))
+		srv.version, srv.standings, srv.pages, srv.templates = version, s, pages, t
+	}
+	return srv.standings, srv.pages, srv.templates, nil
+}
+
+// seriesComp is a competition without its results
+type seriesComp struct {
+	Key        string `json:"key"`
+	Name       string `json:"name"`
+	Date       string `json:"date"`
+	URL        string `json:"url"`
+	Major      bool   `json:"major,omitempty"`
+	NumPlayers int    `json:"numPlayers"`
+}
+
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s, pages, t, err := srv.current()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	path := r.URL.Path
+	switch {
+	case path == "/api/series":
+		series := struct {
+			Year         int          `json:"year"`
+			Options      Options      `json:"options"`
+			Competitions []seriesComp `json:"competitions"`
+			Incomplete   string       `json:"incomplete,omitempty"`
+		}{Year: s.Year, Options: s.Options, Competitions: []seriesComp{},
+			Incomplete: s.Incomplete()}
+		for _, c := range s.byDate() {
+			series.Competitions = append(series.Competitions, seriesComp{Key: c.Key,
+				Name: c.Name, Date: c.Date, URL: c.URL, Major: c.Major, NumPlayers: c.NumPlayers})
+		}
+		writeJSON(w, series)
+	case path == "/api/standings":
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/json")
+		s.WriteJSON(w)
+	case strings.HasPrefix(path, "/api/players/"):
+		name := strings.TrimPrefix(path, "/api/players/")
+		for _, p := range s.OOMResults {
+			if p.Name == name || slug(p.Name) == name {
+				st, err := s.Statement(p.Name)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				writeJSON(w, st)
+				return
+			}
+		}
+		jsonNotFound(w, "no player "+name)
+	case strings.HasPrefix(path, "/api/competitions/"):
+		key := strings.TrimPrefix(path, "/api/competitions/")
+		for _, c := range s.Competitions {
+			if c.Key == key {
+				writeJSON(w, c)
+				return
+			}
+		}
+		jsonNotFound(w, "no competition "+key)
+	case strings.HasPrefix(path, "/api/"):
+		jsonNotFound(w, "no endpoint "+path)
+	default:
+		fname := strings.TrimPrefix(path, "/")
+		if fname == "" {
+			fname = "index.html"
+		}
+		p, ok := pages[fname]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		var b bytes.Buffer
+		if err := t.ExecuteTemplate(&b, p.template, p.page); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(b.Bytes())
+	}
+}
+
+// writeJSON writes v as the response, readable from any site
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func jsonNotFound(w http.ResponseWriter, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,106 @@
+package oom
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oomserve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	comps := []Competition{testComp("1", "Sat 2nd Apr '16", "Jo Mager", "Bea")}
+	loads := 0
+	var loadErr error
+	srv := httptest.NewServer(NewServer(func() (*Standings, error) {
+		loads++
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		return NewStandings(2016, comps, Options{}), nil
+	}, dir, ""))
+	defer srv.Close()
+
+	get := func(path string, wantStatus int, v interface{}) string {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode != wantStatus {
+			t.Errorf("%s: expected status %d, got %d", path, wantStatus, resp.StatusCode)
+		}
+		if v != nil {
+			if err := json.Unmarshal(body, v); err != nil {
+				t.Errorf("%s: %v in %s", path, err, body)
+			}
+		}
+		return string(body)
+	}
+
+	var series struct {
+		Year         int
+		Competitions []struct{ Key string }
+	}
+	get("/api/series", 200, &series)
+	if series.Year != 2016 || len(series.Competitions) != 1 {
+		t.Errorf("expected 2016 with 1 competition, got %+v", series)
+	}
+	var player struct {
+		Rank  string
+		Lines []struct{ Total int }
+	}
+	get("/api/players/jo-mager", 200, &player)
+	if player.Rank != "1" || len(player.Lines) != 1 || player.Lines[0].Total != 2 {
+		t.Errorf("expected Jo Mager 1st with 2 points, got %+v", player)
+	}
+	var comp Competition
+	get("/api/competitions/1", 200, &comp)
+	if comp.Results["Bea"].Rank != 2 {
+		t.Errorf("expected Bea 2nd, got %+v", comp)
+	}
+	var doc Document
+	get("/api/standings", 200, &doc)
+	if doc.Schema != JSONSchema || doc.Standings.RankedPlayers[0] != "Jo Mager" {
+		t.Errorf("expected Jo Mager leading, got %+v", doc)
+	}
+	get("/api/players/nobody", 404, nil)
+	get("/nothing.html", 404, nil)
+	if body := get("/", 200, nil); !strings.Contains(body, `<a href="player-jo-mager.html">Jo Mager</a>`) {
+		t.Errorf("expected the standings page, got %s", body)
+	}
+	if loads != 1 {
+		t.Errorf("expected the standings computed once, got %d", loads)
+	}
+
+	// a new competition in the cache
+	comps = append(comps, testComp("2", "Sat 9th Apr '16", "Bea"))
+	ioutil.WriteFile(filepath.Join(dir, "2.txt"), []byte("x"), 0644)
+	get("/api/series", 200, &series)
+	if loads != 2 || len(series.Competitions) != 2 {
+		t.Errorf("expected the standings recomputed with 2 competitions, got %d and %+v", loads, series)
+	}
+
+	// the standings failing to load, then loading once fixed
+	loadErr = errors.New("oom.conf: no such file")
+	ioutil.WriteFile(filepath.Join(dir, "3.txt"), []byte("x"), 0644)
+	if body := get("/api/series", 500, nil); !strings.Contains(body, "oom.conf") {
+		t.Errorf("expected the error served, got %s", body)
+	}
+	loadErr = nil
+	get("/api/series", 200, &series)
+	if loads != 4 || len(series.Competitions) != 2 {
+		t.Errorf("expected the standings loaded again, got %d and %+v", loads, series)
+	}
+}
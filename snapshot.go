@@ -0,0 +1,213 @@
+package oom
+
+// snapshot.go keeps a copy of the standings from each run so that runs can
+// be compared.  A snapshot is the json Document, saved as KEY.json where
+// KEY is the latest competition included, so rerunning after the same
+// competition replaces its snapshot rather than adding another.
+//
+// Diff compares two snapshots, reporting for each player whose standing
+// changed their rank and points before and after, and the new competitions
+// they scored in
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SnapshotName returns the file name for the standings' snapshot
+func (s *Standings) SnapshotName() string {
+	if latest := s.Latest(); latest != nil {
+		return latest.Key + ".json"
+	}
+	return "none.json"
+}
+
+// SaveSnapshot writes the standings in to dir, which is created if need
+// be, returning the file written
+func (s *Standings) SaveSnapshot(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	fname := filepath.Join(dir, s.SnapshotName())
+	f, err := os.Create(fname)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return fname, s.WriteJSON(f)
+}
+
+// ReadSnapshot reads a Document written by SaveSnapshot or WriteJSON
+func ReadSnapshot(fname string) (*Document, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var doc Document
+	if err := json.NewDecoder(f).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%s: %v", fname, err)
+	}
+	if doc.Schema != JSONSchema || doc.Standings == nil {
+		return nil, fmt.Errorf("%s: not an %s snapshot", fname, JSONSchema)
+	}
+	return &doc, nil
+}
+
+// LatestSnapshots returns the newest n snapshots in dir, oldest first
+func LatestSnapshots(dir string, n int) ([]string, error) {
+	fnames, err := snapshotsByAge(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(fnames) > n {
+		fnames = fnames[len(fnames)-n:]
+	}
+	return fnames, nil
+}
+
+// PruneSnapshots removes all but the newest keep snapshots in dir,
+// returning those removed
+func PruneSnapshots(dir string, keep int) ([]string, error) {
+	fnames, err := snapshotsByAge(dir)
+	if err != nil || len(fnames) <= keep {
+		return nil, err
+	}
+	fnames = fnames[:len(fnames)-keep]
+	for _, fname := range fnames {
+		if err := os.Remove(fname); err != nil {
+			return nil, err
+		}
+	}
+	return fnames, nil
+}
+
+// snapshotFile matches the names SnapshotName gives snapshots
+var snapshotFile = regexp.MustCompile(`^(\d+|none)\.json$`)
+
+// snapshotsByAge returns the snapshots in dir, oldest first, ignoring any
+// other files
+func snapshotsByAge(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var fnames []string
+	modTimes := make(map[string]time.Time)
+	for _, fi := range infos {
+		if fi.Mode().IsRegular() && snapshotFile.MatchString(fi.Name()) {
+			fname := filepath.Join(dir, fi.Name())
+			fnames = append(fnames, fname)
+			modTimes[fname] = fi.ModTime()
+		}
+	}
+	sort.SliceStable(fnames, func(i, j int) bool {
+		return modTimes[fnames[i]].Before(modTimes[fnames[j]])
+	})
+	return fnames, nil
+}
+
+// Change describes how one player's standing changed between two snapshots
+type Change struct {
+	Name      string   `json:"name"`
+	OldRank   string   `json:"oldRank"` // empty for a new entrant
+	NewRank   string   `json:"newRank"` // empty if no longer listed
+	Moved     int      `json:"moved"`   // places climbed, negative if fallen
+	OldPoints int      `json:"oldPoints"`
+	NewPoints int      `json:"newPoints"`
+	NewEntry  bool     `json:"newEntry"`
+	Comps     []string `json:"comps"` // names of the new competitions the player scored in
+}
+
+// StandingsDiff is the difference between two snapshots
+type StandingsDiff struct {
+	NewComps []Competition `json:"newComps"` // in the new but not the old standings
+	Changes  []Change      `json:"changes"`  // in the new rank order
+}
+
+// Diff compares the old and new standings
+func Diff(old *Standings, new *Standings) StandingsDiff {
+	var d StandingsDiff
+	oldComps := make(map[string]bool)
+	for _, comp := range old.Competitions {
+		oldComps[comp.Key] = true
+	}
+	for _, comp := range new.Competitions {
+		if !oldComps[comp.Key] {
+			d.NewComps = append(d.NewComps, comp)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, names := range [][]string{new.RankedPlayers, new.ProvisionalPlayers} {
+		for _, name := range names {
+			seen[name] = true
+			p := new.OOMResults[name]
+			c := Change{Name: name, NewRank: p.RankString(), NewPoints: p.OOMPoints}
+			if o, ok := old.OOMResults[name]; ok {
+				c.OldRank = o.RankString()
+				c.OldPoints = o.OOMPoints
+				c.Moved, _ = new.Movement(old, name)
+			} else {
+				c.NewEntry = true
+			}
+			for _, comp := range d.NewComps {
+				if _, ok := p.PlayerByComp[comp.Key]; ok {
+					c.Comps = append(c.Comps, comp.Name)
+				}
+			}
+			if c.NewEntry || c.OldRank != c.NewRank || c.OldPoints != c.NewPoints {
+				d.Changes = append(d.Changes, c)
+			}
+		}
+	}
+	for _, names := range [][]string{old.RankedPlayers, old.ProvisionalPlayers} {
+		for _, name := range names {
+			if !seen[name] {
+				o := old.OOMResults[name]
+				d.Changes = append(d.Changes, Change{Name: name, OldRank: o.RankString(),
+					OldPoints: o.OOMPoints})
+			}
+		}
+	}
+	return d
+}
+
+// WriteText writes the diff as plain text, a line per player
+func (d StandingsDiff) WriteText(w io.Writer) error {
+	var names []string
+	for _, comp := range d.NewComps {
+		names = append(names, fmt.Sprintf("%s (%s)", comp.Name, comp.Date))
+	}
+	if len(names) > 0 {
+		fmt.Fprintf(w, "New competitions: %s\n", strings.Join(names, ", "))
+	}
+	if len(d.Changes) == 0 {
+		_, err := fmt.Fprintln(w, "No change in the standings")
+		return err
+	}
+	var rows [][]string
+	rows = append(rows, []string{"Rank", "Name", "Was", "Move", "Points", "Gained", "Scored in"})
+	for _, c := range d.Changes {
+		move := formatMove(c.Moved)
+		switch {
+		case c.NewEntry:
+			move = "new"
+		case c.NewRank == "":
+			move = "out"
+		}
+		rows = append(rows, []string{c.NewRank, c.Name, c.OldRank, move,
+			fmt.Sprintf("%d", c.NewPoints), fmt.Sprintf("%+d", c.NewPoints-c.OldPoints),
+			strings.Join(c.Comps, ", ")})
+	}
+	writeTable(w, rows, false, 1, 6)
+	return nil
+}
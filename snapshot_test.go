@@ -0,0 +1,84 @@
+package oom
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshotDiff(t *testing.T) {
+	comps := []Competition{
+		testComp("1", "Sat 2nd Apr '16", "Ann", "Bea", "Cat"),
+		testComp("2", "Sat 9th Apr '16", "Cat", "Dot", "Bea"),
+	}
+	dir, err := ioutil.TempDir("", "oomsnap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	old := NewStandings(2016, comps[:1], Options{})
+	fname, err := old.SaveSnapshot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ReadSnapshot(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := Diff(doc.Standings, NewStandings(2016, comps, Options{}))
+	if len(d.NewComps) != 1 || d.NewComps[0].Key != "2" {
+		t.Errorf("expected comp 2 to be new, got %v", d.NewComps)
+	}
+	var buf bytes.Buffer
+	d.WriteText(&buf)
+	want := `New competitions: comp 2 (Sat 9th Apr '16)
+Rank  Name  Was  Move  Points  Gained  Scored in
+----  ----  ---  ----  ------  ------  ---------
+   1  Cat     3    +2       4      +3  comp 2
+  T2  Ann     1    -1       3      +0
+  T2  Bea     2     =       3      +1  comp 2
+   4  Dot         new       2      +2  comp 2
+`
+	if buf.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, buf.String())
+	}
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	for i, key := range []string{"3", "1", "2"} {
+		fname := filepath.Join(dir, key+".json")
+		ioutil.WriteFile(fname, []byte("{}"), 0644)
+		when := time.Date(2016, 4, 1+i, 0, 0, 0, 0, time.UTC)
+		os.Chtimes(fname, when, when)
+	}
+	// older, but not named as a snapshot
+	for _, name := range []string{"standings.json", "1266-old.json"} {
+		fname := filepath.Join(dir, name)
+		ioutil.WriteFile(fname, []byte("{}"), 0644)
+		when := time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC)
+		os.Chtimes(fname, when, when)
+	}
+	removed, err := PruneSnapshots(dir, 2)
+	if err != nil || len(removed) != 1 || filepath.Base(removed[0]) != "3.json" {
+		t.Errorf("expected the oldest, 3.json, removed, got %v %v", removed, err)
+	}
+	if fnames, _ := LatestSnapshots(dir, 5); len(fnames) != 2 || filepath.Base(fnames[1]) != "2.json" {
+		t.Errorf("expected 1.json and 2.json kept, got %v", fnames)
+	}
+	if removed, err := PruneSnapshots(dir, 2); err != nil || len(removed) != 0 {
+		t.Errorf("expected nothing more removed, got %v %v", removed, err)
+	}
+	for _, name := range []string{"standings.json", "1266-old.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s kept, got %v", name, err)
+		}
+	}
+	if fnames, err := LatestSnapshots(filepath.Join(dir, "missing"), 2); err != nil || len(fnames) != 0 {
+		t.Errorf("expected no snapshots in a missing directory, got %v %v", fnames, err)
+	}
+}
@@ -0,0 +1,413 @@
+package oom
+
+// standings.go builds the order of merit table from a set of loaded
+// competitions.  The competitions are transposed in to a map keyed by
+// player, each player's best scores are summed and the players ranked.
+// Nothing here touches the network or the file system so the same
+// Standings can be used by the oom binary and by any other tool that
+// wants the table without parsing out.csv
+//
+// Players on equal points share a rank (shown as T3) unless separated by
+// the tie-breakers listed in Options.TieBreakers, which are applied in
+// order to each group of tied players
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PlayerOOM represents one player's standing across all the competitions
+type PlayerOOM struct {
+	Name            string                  `json:"name"`
+	ID              string                  `json:"id,omitempty"` // playerid on the website, if known
+	Rank            int                     `json:"rank"`
+	OOMPoints       int                     `json:"oomPoints"`
+	PointsSlice     []int                   `json:"points"` // all the points, sorted best first
+	NumCompetitions int                     `json:"numCompetitions"`
+	PlayerByComp    map[string]PlayerResult `json:"results"`            // map keyed on comp key, with Counted set
+	Tied            bool                    `json:"tied"`               // shares Rank with another player
+	TieBreak        TieBreaker              `json:"tieBreak,omitempty"` // tie-breaker that decided Rank, if any
+	Provisional     bool                    `json:"provisional"`        // played fewer than Options.MinComps
+	NumCounted      int                     `json:"numCounted"`         // scores counting towards OOMPoints
+	ToImprove       int                     `json:"toImprove"`          // a new score must beat this to add points
+}
+
+// RankString returns the rank for display e.g. "3", or "T3" if tied.  A
+// provisional player's rank is marked with a trailing "*"
+func (p PlayerOOM) RankString() string {
+	s := fmt.Sprintf("%d", p.Rank)
+	if p.Tied {
+		s = "T" + s
+	}
+	if p.Provisional {
+		s += "*"
+	}
+	return s
+}
+
+// TieBreaker identifies a rule used to separate players on equal points
+type TieBreaker int
+
+// The available tie-breakers - the larger value wins in each case
+const (
+	MostPlayed TieBreaker = iota + 1 // most competitions played
+	BestScore                        // best single oom points score
+	MostWins                         // most competitions won
+	BestLastN                        // most points in the last LastN competitions
+	HeadToHead                       // most wins against the other tied players
+)
+
+var tieBreakerNames = map[TieBreaker]string{
+	MostPlayed: "played",
+	BestScore:  "best",
+	MostWins:   "wins",
+	BestLastN:  "lastN",
+	HeadToHead: "h2h",
+}
+
+func (t TieBreaker) String() string {
+	return tieBreakerNames[t]
+}
+
+// MarshalText writes the tie-breaker by name in the json output
+func (t TieBreaker) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText reads a tie-breaker name
+func (t *TieBreaker) UnmarshalText(text []byte) error {
+	tbs, err := ParseTieBreakers(string(text))
+	if err != nil || len(tbs) != 1 {
+		return errors.New("oom: invalid tie-breaker " + string(text))
+	}
+	*t = tbs[0]
+	return nil
+}
+
+// ParseTieBreakers converts a comma separated list of tie-breaker names
+// e.g. "played,wins,h2h" in to the []TieBreaker to apply in that order
+func ParseTieBreakers(s string) ([]TieBreaker, error) {
+	var ret []TieBreaker
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for t, tName := range tieBreakerNames {
+			if strings.EqualFold(name, tName) {
+				ret = append(ret, t)
+				found = true
+			}
+		}
+		if !found {
+			return nil, errors.New("oom: unknown tie-breaker " + name)
+		}
+	}
+	return ret, nil
+}
+
+// Options controls how the standings are calculated
+type Options struct {
+	MaxComps    int          `json:"maxComps"`    // best MaxComps scores count, 0 means count them all
+	TieBreakers []TieBreaker `json:"tieBreakers"` // applied in order to players on equal points
+	LastN       int          `json:"lastN"`       // number of competitions for BestLastN, default 3
+	MinComps    int          `json:"minComps"`    // competitions needed to qualify, fewer is provisional
+	PadToMin    bool         `json:"padToMin"`    // zero pad provisional scores and rank with the rest
+	Period      Period       `json:"period"`      // the season is split in to periods for PerPeriod
+	PerPeriod   int          `json:"perPeriod"`   // best PerPeriod scores count in each Period, 0 no limit
+	MinMajors   int          `json:"minMajors"`   // best MinMajors scores from major competitions always count
+}
+
+// Standings is the order of merit for a set of competitions
+type Standings struct {
+	Year          int                  `json:"year"`
+	Competitions  []Competition        `json:"competitions"`
+	RankedPlayers []string             `json:"rankedPlayers"` // first to last in results
+	OOMResults    map[string]PlayerOOM `json:"players"`       // map keyed by player name
+	Options       Options              `json:"options"`
+	// ProvisionalPlayers are those yet to play Options.MinComps, ranked
+	// among themselves.  Empty if Options.PadToMin is set
+	ProvisionalPlayers []string `json:"provisionalPlayers"`
+	// Missing are the competitions that couldn't be loaded, when Offline,
+	// so the standings are incomplete
+	Missing []Competition `json:"missing,omitempty"`
+}
+
+// NewStandings returns the standings for the competitions, which should
+// already have been populated by Load.  Any that weren't, having no
+// Results, are left out and listed in Missing
+func NewStandings(year int, comps []Competition, opts Options) *Standings {
+	s := &Standings{Year: year, Options: opts}
+	for _, comp := range comps {
+		if comp.Results == nil {
+			s.Missing = append(s.Missing, comp)
+		} else {
+			s.Competitions = append(s.Competitions, comp)
+		}
+	}
+	s.populate()
+	s.rank()
+	Logger.Debug("computed standings", "year", year, "competitions", len(s.Competitions),
+		"missing", len(s.Missing), "players", len(s.RankedPlayers))
+	return s
+}
+
+// Incomplete returns a description of the missing competitions e.g.
+// "Incomplete - missing elmstead (1266)", or an empty string if there are
+// none
+func (s *Standings) Incomplete() string {
+	if len(s.Missing) == 0 {
+		return ""
+	}
+	var titles []string
+	for _, comp := range s.Missing {
+		titles = append(titles, comp.Title())
+	}
+	return "Incomplete - missing " + strings.Join(titles, ", ")
+}
+
+// populate transposes the data from the []Competitions in to the map
+// keyed by player
+func (s *Standings) populate() {
+	s.OOMResults = make(map[string]PlayerOOM)
+	for i := range s.Competitions {
+		comp := &s.Competitions[i] // Competitions is a slice
+		for name, result := range comp.Results {
+			// if player not seen before initialise their PlayerOOM entry
+			playerOOM, ok := s.OOMResults[name]
+			if !ok {
+				playerOOM.Name = name
+				playerOOM.PointsSlice = []int{}
+				playerOOM.PlayerByComp = make(map[string]PlayerResult)
+			}
+			if playerOOM.ID == "" {
+				playerOOM.ID = result.ID
+			}
+			playerOOM.PlayerByComp[comp.Key] = PlayerResult{
+				Name:      name,
+				ID:        result.ID,
+				OOMPoints: result.OOMPoints,
+				Rank:      result.Rank,
+				Result:    result.Result,
+			}
+			playerOOM.OOMPoints += result.OOMPoints // counting every comp
+			// Also keep a slice with all the points for later sort/cap len/sum
+			playerOOM.PointsSlice = append(playerOOM.PointsSlice, result.OOMPoints)
+			playerOOM.NumCompetitions++
+			s.OOMResults[name] = playerOOM
+		}
+	}
+}
+
+// rank sums the points counted under the rules in counting.go and orders
+// the players, highest points first.  Players on equal points are ordered
+// by the tie-breakers and then by name, so the order is the same every run.
+// Players short of Options.MinComps are ranked separately in
+// ProvisionalPlayers unless Options.PadToMin is set
+func (s *Standings) rank() {
+	// On entry OOMPoints is the sum of points from all comps - first task
+	// to cap this to those that count
+	var names, provisional []string
+	for name, oomRes := range s.OOMResults {
+		oomRes.Provisional = oomRes.NumCompetitions < s.Options.MinComps
+		if oomRes.Provisional && s.Options.PadToMin {
+			for len(oomRes.PointsSlice) < s.Options.MinComps {
+				oomRes.PointsSlice = append(oomRes.PointsSlice, 0)
+			}
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(oomRes.PointsSlice)))
+		counted := s.selectCounted(oomRes)
+		oomRes.OOMPoints = 0
+		oomRes.NumCounted = len(counted)
+		oomRes.ToImprove = 0
+		lowest := -1
+		for key, r := range oomRes.PlayerByComp {
+			r.Counted = counted[key]
+			if r.Counted {
+				oomRes.OOMPoints += r.OOMPoints
+				if lowest == -1 || r.OOMPoints < lowest {
+					lowest = r.OOMPoints
+				}
+			}
+			oomRes.PlayerByComp[key] = r
+		}
+		// once the best MaxComps are counting a new score has to beat the
+		// lowest of them, otherwise any score adds to the total
+		if s.Options.MaxComps > 0 && oomRes.NumCounted >= s.Options.MaxComps {
+			oomRes.ToImprove = lowest
+		}
+		oomRes.Tied = false
+		oomRes.TieBreak = 0
+		// oomRes is a copy of the structure - need to overwrite original
+		s.OOMResults[name] = oomRes
+		if oomRes.Provisional && !s.Options.PadToMin {
+			provisional = append(provisional, name)
+		} else {
+			names = append(names, name)
+		}
+	}
+	s.RankedPlayers = s.order(names)
+	s.ProvisionalPlayers = s.order(provisional)
+}
+
+// order sorts the named players and sets their Rank, starting from 1
+func (s *Standings) order(names []string) []string {
+	sort.Slice(names, func(i, j int) bool {
+		pi, pj := s.OOMResults[names[i]].OOMPoints, s.OOMResults[names[j]].OOMPoints
+		if pi != pj {
+			return pi > pj
+		}
+		return names[i] < names[j]
+	})
+
+	var ranked []string
+	for start := 0; start < len(names); {
+		end := start + 1
+		for end < len(names) &&
+			s.OOMResults[names[end]].OOMPoints == s.OOMResults[names[start]].OOMPoints {
+			end++
+		}
+		for _, group := range s.breakTies(names[start:end], s.Options.TieBreakers) {
+			rank := len(ranked) + 1
+			for _, name := range group {
+				pOOM := s.OOMResults[name]
+				pOOM.Rank = rank // can't directly assign to struct field within map
+				pOOM.Tied = len(group) > 1
+				s.OOMResults[name] = pOOM
+				ranked = append(ranked, name)
+			}
+		}
+		start = end
+	}
+	return ranked
+}
+
+// breakTies orders a group of players on equal points using the first of
+// the tie-breakers, then recursively applies the remaining tie-breakers to
+// any players still level.  It returns the groups still tied, best first.
+// Each player a tie-breaker separates from all the others in the group has
+// TieBreak set to it, those left tied having none
+func (s *Standings) breakTies(group []string, breakers []TieBreaker) [][]string {
+	if len(group) < 2 || len(breakers) == 0 {
+		return [][]string{group}
+	}
+	tb := breakers[0]
+	keys := make(map[string]int)
+	for _, name := range group {
+		keys[name] = s.tieKey(tb, name, group)
+	}
+	sort.SliceStable(group, func(i, j int) bool { return keys[group[i]] > keys[group[j]] })
+	var ret [][]string
+	for start := 0; start < len(group); {
+		end := start + 1
+		for end < len(group) && keys[group[end]] == keys[group[start]] {
+			end++
+		}
+		if end-start == 1 { // tb has separated this player from the rest
+			pOOM := s.OOMResults[group[start]]
+			pOOM.TieBreak = tb
+			s.OOMResults[group[start]] = pOOM
+		}
+		ret = append(ret, s.breakTies(group[start:end], breakers[1:])...)
+		start = end
+	}
+	return ret
+}
+
+// tieKey returns the value of the tie-breaker for the named player, the
+// larger the better.  group is the set of players currently tied
+func (s *Standings) tieKey(tb TieBreaker, name string, group []string) int {
+	p := s.OOMResults[name]
+	key := 0
+	switch tb {
+	case MostPlayed:
+		key = p.NumCompetitions
+	case BestScore:
+		for _, r := range p.PlayerByComp {
+			if r.OOMPoints > key {
+				key = r.OOMPoints
+			}
+		}
+	case MostWins:
+		for _, r := range p.PlayerByComp {
+			if r.Rank == 1 {
+				key++
+			}
+		}
+	case BestLastN:
+		lastN := s.Options.LastN
+		if lastN <= 0 {
+			lastN = 3
+		}
+		comps := s.byDate()
+		if len(comps) > lastN {
+			comps = comps[len(comps)-lastN:]
+		}
+		for _, comp := range comps {
+			key += p.PlayerByComp[comp.Key].OOMPoints
+		}
+	case HeadToHead:
+		// a mini-league: one point per competition finishing ahead of
+		// another of the tied players
+		for _, other := range group {
+			if other == name {
+				continue
+			}
+			for compKey, r := range p.PlayerByComp {
+				if r2, ok := s.OOMResults[other].PlayerByComp[compKey]; ok && r.Rank < r2.Rank {
+					key++
+				}
+			}
+		}
+	}
+	return key
+}
+
+// byDate returns a copy of the competitions in date order.  Competitions
+// with a date that can't be parsed keep their position relative to each
+// other
+func (s *Standings) byDate() []Competition {
+	comps := make([]Competition, len(s.Competitions))
+	copy(comps, s.Competitions)
+	sort.SliceStable(comps, func(i, j int) bool {
+		return comps[i].Time().Before(comps[j].Time())
+	})
+	return comps
+}
+
+// formatPlayerResult returns the oom points, optionally followed by the
+// position and result e.g. "24 (3rd 38)".  Points that don't count towards
+// the total are in brackets e.g. "[9] (20th 31)"
+func formatPlayerResult(p PlayerResult, detail bool) string {
+	points := fmt.Sprintf("%d", p.OOMPoints)
+	if !p.Counted {
+		points = "[" + points + "]"
+	}
+	if !detail {
+		return points
+	}
+	return fmt.Sprintf("%s (%s %s)", points, ordinal(p.Rank), p.Result)
+}
+
+// ordinal returns 1st, 2nd, 3rd, 4th ... 11th, 12th, 13th ... 21st
+func ordinal(n int) string {
+	nth := "th"
+	s := fmt.Sprintf("%d", n)
+	l := s[len(s)-1:]
+	if l == "1" {
+		nth = "st"
+	}
+	if l == "2" {
+		nth = "nd"
+	}
+	if l == "3" {
+		nth = "rd"
+	}
+	if n%100 > 10 && n%100 < 20 {
+		nth = "th"
+	}
+	return s + nth
+}
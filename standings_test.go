@@ -0,0 +1,172 @@
+package oom
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// testComp builds a loaded Competition from names in finishing order,
+// awarding points the same way as populateResultsFromWeb
+func testComp(key string, date string, names ...string) Competition {
+	c := Competition{Key: key, Name: "comp " + key, Date: date,
+		NumPlayers: len(names), Results: make(map[string]PlayerResult)}
+	for n, name := range names {
+		c.Results[name] = PlayerResult{Name: name, Rank: n + 1,
+			OOMPoints: len(names) - n, Result: "36"}
+	}
+	return c
+}
+
+func TestNewStandings(t *testing.T) {
+	comps := []Competition{
+		testComp("1", "Sat 2nd Apr '16", "Ann", "Bea", "Cat"),
+		testComp("2", "Sat 9th Apr '16", "Bea", "Cat", "Ann"),
+		testComp("3", "Sat 16th Apr '16", "Bea", "Ann"),
+	}
+	s := NewStandings(2016, comps, Options{MaxComps: 2})
+	bea := s.OOMResults["Bea"]
+	if bea.OOMPoints != 5 || bea.NumCompetitions != 3 || bea.Rank != 1 {
+		t.Errorf("Bea: expected 5 points from 3 comps ranked 1, got %+v", bea)
+	}
+	if s.RankedPlayers[0] != "Bea" {
+		t.Errorf("expected Bea to lead, got %v", s.RankedPlayers)
+	}
+	var buf bytes.Buffer
+	if err := s.WriteCSV(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "3 (1st 36)") {
+		t.Errorf("expected detailed result in csv, got\n%s", buf.String())
+	}
+}
+
+func TestOrdinal(t *testing.T) {
+	for n, want := range map[int]string{1: "1st", 2: "2nd", 3: "3rd", 4: "4th",
+		11: "11th", 12: "12th", 13: "13th", 21: "21st", 112: "112th"} {
+		if got := ordinal(n); got != want {
+			t.Errorf("ordinal(%d): expected %s, got %s", n, want, got)
+		}
+	}
+}
+
+func TestTieBreakers(t *testing.T) {
+	comps := []Competition{
+		testComp("1", "Sat 2nd Apr '16", "Ann", "Bea", "Cat", "Dot"),
+		testComp("2", "Sat 9th Apr '16", "Dot", "Cat", "Bea", "Ann"),
+	}
+	// every player has 5 points so all share first place by default
+	s := NewStandings(2016, comps, Options{})
+	for _, name := range []string{"Ann", "Bea", "Cat", "Dot"} {
+		if p := s.OOMResults[name]; p.RankString() != "T1" {
+			t.Errorf("%s: expected T1, got %s", name, p.RankString())
+		}
+	}
+	if s.RankedPlayers[0] != "Ann" || s.RankedPlayers[3] != "Dot" {
+		t.Errorf("expected tied players in name order, got %v", s.RankedPlayers)
+	}
+
+	// best single score splits Ann and Dot (4) from Bea and Cat (3), but
+	// head to head is one all in each pair, so no rule separates anyone
+	checkRanks(t, NewStandings(2016, comps,
+		Options{TieBreakers: []TieBreaker{BestScore, HeadToHead}}),
+		"T1 Ann ", "T1 Dot ", "T3 Bea ", "T3 Cat ")
+	// whereas the last competition separates both pairs
+	checkRanks(t, NewStandings(2016, comps,
+		Options{TieBreakers: []TieBreaker{BestScore, BestLastN}, LastN: 1}),
+		"1 Dot lastN", "2 Ann lastN", "3 Cat lastN", "4 Bea lastN")
+
+	// wins separate Bea, who has none, from Ann and Cat, left tied
+	comps = []Competition{
+		testComp("1", "Sat 2nd Apr '16", "Ann", "Bea", "Cat"),
+		testComp("2", "Sat 9th Apr '16", "Cat", "Bea", "Ann"),
+	}
+	checkRanks(t, NewStandings(2016, comps, Options{TieBreakers: []TieBreaker{MostWins}}),
+		"T1 Ann ", "T1 Cat ", "3 Bea wins")
+}
+
+// checkRanks compares the "rank name tiebreak" of each ranked player
+func checkRanks(t *testing.T, s *Standings, want ...string) {
+	t.Helper()
+	for n, name := range s.RankedPlayers {
+		p := s.OOMResults[name]
+		if got := p.RankString() + " " + name + " " + p.TieBreak.String(); got != want[n] {
+			t.Errorf("position %d: expected %s, got %s", n+1, want[n], got)
+		}
+	}
+}
+
+func TestParseTieBreakers(t *testing.T) {
+	tbs, err := ParseTieBreakers("played, wins,lastN")
+	if err != nil || len(tbs) != 3 || tbs[0] != MostPlayed || tbs[2] != BestLastN {
+		t.Errorf("expected [played wins lastN], got %v %v", tbs, err)
+	}
+	if _, err := ParseTieBreakers("coin toss"); err == nil {
+		t.Error("expected error for unknown tie-breaker")
+	}
+}
+
+func TestMinComps(t *testing.T) {
+	comps := []Competition{
+		testComp("1", "Sat 2nd Apr '16", "Ann", "Bea", "Cat"),
+		testComp("2", "Sat 9th Apr '16", "Bea", "Cat"),
+	}
+	s := NewStandings(2016, comps, Options{MinComps: 2})
+	if len(s.RankedPlayers) != 2 || len(s.ProvisionalPlayers) != 1 {
+		t.Fatalf("expected 2 qualified and 1 provisional, got %v %v",
+			s.RankedPlayers, s.ProvisionalPlayers)
+	}
+	if p := s.OOMResults["Ann"]; p.RankString() != "1*" {
+		t.Errorf("expected Ann ranked 1* in provisional section, got %s", p.RankString())
+	}
+	var buf bytes.Buffer
+	s.WriteCSV(&buf, false)
+	if !strings.Contains(buf.String(), "Provisional - fewer than 2 competitions\n1*,Ann,3,1,3,,0\n") {
+		t.Errorf("expected provisional section in csv, got\n%s", buf.String())
+	}
+
+	s = NewStandings(2016, comps, Options{MinComps: 2, PadToMin: true})
+	if len(s.ProvisionalPlayers) != 0 || s.OOMResults["Ann"].RankString() != "2*" ||
+		len(s.OOMResults["Ann"].PointsSlice) != 2 {
+		t.Errorf("expected Ann padded and ranked 2*, got %+v", s.OOMResults["Ann"])
+	}
+}
+
+func TestToImprove(t *testing.T) {
+	comps := []Competition{
+		testComp("1", "Sat 2nd Apr '16", "Ann", "Bea", "Cat"),
+		testComp("2", "Sat 9th Apr '16", "Bea", "Ann", "Cat"),
+		testComp("3", "Sat 16th Apr '16", "Cat", "Ann", "Dot"),
+	}
+	s := NewStandings(2016, comps, Options{MaxComps: 2})
+	// Ann scored 3, 2 and 2 so needs better than 2 to improve, Dot has
+	// only one score so anything helps
+	if ann := s.OOMResults["Ann"]; ann.ToImprove != 2 || ann.NumCounted != 2 {
+		t.Errorf("Ann: expected 2 counted needing 2, got %+v", ann)
+	}
+	if dot := s.OOMResults["Dot"]; dot.ToImprove != 0 {
+		t.Errorf("Dot: expected 0 to improve, got %d", dot.ToImprove)
+	}
+	var buf bytes.Buffer
+	s.WriteCSV(&buf, false)
+	if !strings.Contains(buf.String(), ",Ann,5,3,3,2,[2],2\n") {
+		t.Errorf("expected Ann's dropped score and toImprove in csv, got\n%s", buf.String())
+	}
+}
+
+func TestMissing(t *testing.T) {
+	comps := []Competition{
+		testComp("1", "Sat 2nd Apr '16", "Ann", "Bea"),
+		{Key: "2", Label: "elmstead"},
+	}
+	s := NewStandings(2016, comps, Options{MaxComps: 10})
+	if len(s.Competitions) != 1 || len(s.Missing) != 1 {
+		t.Fatalf("expected 1 competition and 1 missing, got %v and %v", s.Competitions, s.Missing)
+	}
+	if got := s.Incomplete(); got != "Incomplete - missing elmstead (2)" {
+		t.Errorf("expected Incomplete - missing elmstead (2), got %s", got)
+	}
+	if got := NewStandings(2016, comps[:1], Options{}).Incomplete(); got != "" {
+		t.Errorf("expected complete standings, got %s", got)
+	}
+}
@@ -0,0 +1,191 @@
+package oom
+
+// statement.go explains a player's standing, for members asking "why am I
+// 14th?".  The statement lists every competition the player entered in
+// date order with their result, position, field size and points, whether
+// the points counted, and the player's running total and rank after each
+// event - taken from the standings recalculated after each competition.
+// It ends with the gap to the players above and below
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// History returns the standings recalculated after each competition in
+// date order, using the same options.  The last is equivalent to s
+func (s *Standings) History() []*Standings {
+	comps := s.byDate()
+	var ret []*Standings
+	for n := range comps {
+		ret = append(ret, NewStandings(s.Year, comps[:n+1], s.Options))
+	}
+	return ret
+}
+
+// StatementLine is one competition on a player's statement
+type StatementLine struct {
+	Comp   *Competition
+	Result PlayerResult // Counted as in the final standings
+	Total  int          // running total after this competition
+	Rank   string       // rank after this competition e.g. T3
+}
+
+// Statement is a player's standing, competition by competition
+type Statement struct {
+	Standings *Standings
+	Player    PlayerOOM
+	Lines     []StatementLine
+	Above     *PlayerOOM // next player up the table, nil if leading
+	Below     *PlayerOOM // next player down the table, nil if last
+}
+
+// GapAbove returns the points needed to draw level with the player above
+func (st *Statement) GapAbove() int {
+	if st.Above == nil {
+		return 0
+	}
+	return st.Above.OOMPoints - st.Player.OOMPoints
+}
+
+// GapBelow returns the player's lead over the player below
+func (st *Statement) GapBelow() int {
+	if st.Below == nil {
+		return 0
+	}
+	return st.Player.OOMPoints - st.Below.OOMPoints
+}
+
+// Statement returns the named player's statement
+func (s *Standings) Statement(name string) (*Statement, error) {
+	return s.statement(name, s.History())
+}
+
+// statement builds the player's statement from the standings history
+func (s *Standings) statement(name string, history []*Standings) (*Statement, error) {
+	p, ok := s.OOMResults[name]
+	if !ok {
+		return nil, fmt.Errorf("oom: no results for player %s", name)
+	}
+	st := &Statement{Standings: s, Player: p}
+	for _, names := range [][]string{s.RankedPlayers, s.ProvisionalPlayers} {
+		for n, other := range names {
+			if other != name {
+				continue
+			}
+			if n > 0 {
+				above := s.OOMResults[names[n-1]]
+				st.Above = &above
+			}
+			if n < len(names)-1 {
+				below := s.OOMResults[names[n+1]]
+				st.Below = &below
+			}
+		}
+	}
+	for _, after := range history {
+		comp := after.Latest()
+		r, ok := p.PlayerByComp[comp.Key]
+		if !ok {
+			continue
+		}
+		pAfter := after.OOMResults[name]
+		st.Lines = append(st.Lines, StatementLine{Comp: comp, Result: r,
+			Total: pAfter.OOMPoints, Rank: pAfter.RankString()})
+	}
+	return st, nil
+}
+
+// WriteText writes the statement as fixed width plain text
+func (st *Statement) WriteText(w io.Writer) error {
+	p := st.Player
+	fmt.Fprintf(w, "%s - Order of Merit %d\n", p.Name, st.Standings.Year)
+	if incomplete := st.Standings.Incomplete(); incomplete != "" {
+		fmt.Fprintln(w, incomplete)
+	}
+	fmt.Fprintf(w, "Rank %s with %d points from %d of %d competitions\n\n",
+		p.RankString(), p.OOMPoints, p.NumCounted, p.NumCompetitions)
+	rows := [][]string{{"Date", "Competition", "Result", "Position", "Field",
+		"Points", "Total", "Rank"}}
+	for _, line := range st.Lines {
+		rows = append(rows, []string{line.Comp.Date, line.Comp.Name, line.Result.Result,
+			ordinal(line.Result.Rank), fmt.Sprintf("%d", line.Comp.NumPlayers),
+			formatPlayerResult(line.Result, false), fmt.Sprintf("%d", line.Total), line.Rank})
+	}
+	writeTable(w, rows, false, 0, 1)
+	fmt.Fprintln(w)
+	for _, gap := range st.Gaps() {
+		fmt.Fprintln(w, gap)
+	}
+	if p.ToImprove > 0 {
+		fmt.Fprintf(w, "A score of more than %d points will improve your total\n", p.ToImprove)
+	}
+	_, err := fmt.Fprintln(w, "Scores in [brackets] do not count towards the total")
+	return err
+}
+
+// Gaps describes the gap to the players above and below
+func (st *Statement) Gaps() []string {
+	var ret []string
+	if st.Above != nil {
+		ret = append(ret, fmt.Sprintf("%s behind %s (%s)", plural(st.GapAbove(), "point"),
+			st.Above.Name, st.Above.RankString()))
+	}
+	if st.Below != nil {
+		if gap := st.GapBelow(); gap > 0 {
+			ret = append(ret, fmt.Sprintf("%s ahead of %s (%s)", plural(gap, "point"),
+				st.Below.Name, st.Below.RankString()))
+		} else {
+			ret = append(ret, fmt.Sprintf("Level with %s (%s)", st.Below.Name,
+				st.Below.RankString()))
+		}
+	}
+	return ret
+}
+
+// WriteHTML writes the statement as a standalone html page using the
+// player.html template, see html.go
+func (st *Statement) WriteHTML(w io.Writer, templateDir string) error {
+	t, err := htmlTemplates(templateDir)
+	if err != nil {
+		return err
+	}
+	return t.ExecuteTemplate(w, "player.html", htmlPage{Title: st.Player.Name,
+		Standings: st.Standings, Player: &st.Player, Statement: st})
+}
+
+// MarshalJSON writes the statement without the standings it was taken
+// from
+func (st *Statement) MarshalJSON() ([]byte, error) {
+	type line struct {
+		Key    string       `json:"key"`
+		Name   string       `json:"name"`
+		Date   string       `json:"date"`
+		Field  int          `json:"field"`
+		Result PlayerResult `json:"result"`
+		Total  int          `json:"total"`
+		Rank   string       `json:"rank"`
+	}
+	doc := struct {
+		Year       int       `json:"year"`
+		Player     PlayerOOM `json:"player"`
+		Rank       string    `json:"rank"`
+		Lines      []line    `json:"lines"`
+		Gaps       []string  `json:"gaps"`
+		Incomplete string    `json:"incomplete,omitempty"`
+	}{Year: st.Standings.Year, Player: st.Player, Rank: st.Player.RankString(),
+		Lines: []line{}, Gaps: st.Gaps(), Incomplete: st.Standings.Incomplete()}
+	for _, l := range st.Lines {
+		doc.Lines = append(doc.Lines, line{Key: l.Comp.Key, Name: l.Comp.Name, Date: l.Comp.Date,
+			Field: l.Comp.NumPlayers, Result: l.Result, Total: l.Total, Rank: l.Rank})
+	}
+	return json.Marshal(doc)
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,39 @@
+package oom
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStatement(t *testing.T) {
+	comps := []Competition{
+		testComp("2", "Sat 9th Apr '16", "Cat", "Dot", "Bea"),
+		testComp("1", "Sat 2nd Apr '16", "Ann", "Bea", "Cat"),
+	}
+	s := NewStandings(2016, comps, Options{MaxComps: 1})
+	if _, err := s.Statement("Eve"); err == nil {
+		t.Error("expected error for unknown player")
+	}
+	st, err := s.Statement("Bea")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	st.WriteText(&buf)
+	want := `Bea - Order of Merit 2016
+Rank T3 with 2 points from 1 of 2 competitions
+
+Date             Competition  Result  Position  Field  Points  Total  Rank
+---------------  -----------  ------  --------  -----  ------  -----  ----
+Sat 2nd Apr '16  comp 1           36       2nd      3       2      2     2
+Sat 9th Apr '16  comp 2           36       3rd      3     [1]      2    T3
+
+1 point behind Cat (T1)
+Level with Dot (T3)
+A score of more than 2 points will improve your total
+Scores in [brackets] do not count towards the total
+`
+	if buf.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, buf.String())
+	}
+}
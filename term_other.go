@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+
+package oom
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// noEcho can't turn off the echo here
+func noEcho(fd uintptr) (restore func() error, err error) {
+	return nil, fmt.Errorf("no terminal echo control on %s", runtime.GOOS)
+}
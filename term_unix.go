@@ -0,0 +1,32 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package oom
+
+// term_unix.go turns off the terminal echo with the termios ioctls
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// noEcho turns off the echo on the terminal fd, returning how to turn it
+// back on
+func noEcho(fd uintptr) (restore func() error, err error) {
+	var old syscall.Termios
+	if err := termios(fd, ioctlGetTermios, &old); err != nil {
+		return nil, err
+	}
+	t := old
+	t.Lflag &^= syscall.ECHO
+	if err := termios(fd, ioctlSetTermios, &t); err != nil {
+		return nil, err
+	}
+	return func() error { return termios(fd, ioctlSetTermios, &old) }, nil
+}
+
+func termios(fd uintptr, req uintptr, t *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return errno
+	}
+	return nil
+}
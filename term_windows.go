@@ -0,0 +1,29 @@
+package oom
+
+// term_windows.go turns off the console echo with the console mode
+
+import "syscall"
+
+const enableEchoInput = 0x4
+
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// noEcho turns off the echo on the console fd, returning how to turn it
+// back on
+func noEcho(fd uintptr) (restore func() error, err error) {
+	var mode uint32
+	if err := syscall.GetConsoleMode(syscall.Handle(fd), &mode); err != nil {
+		return nil, err
+	}
+	if err := consoleMode(fd, mode&^enableEchoInput); err != nil {
+		return nil, err
+	}
+	return func() error { return consoleMode(fd, mode) }, nil
+}
+
+func consoleMode(fd uintptr, mode uint32) error {
+	if ok, _, err := setConsoleMode.Call(fd, uintptr(mode)); ok == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package oom
+
+// text.go renders the standings as text for pasting in to the members'
+// email or a WhatsApp group, either as a Markdown table or as fixed width
+// plain text.  Along with the table it shows the winners of the latest
+// competition and who moved since it was played
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// TextOptions controls the text output
+type TextOptions struct {
+	Markdown bool // Markdown table, otherwise fixed width plain text
+	Top      int  // only show the top players, 0 shows everyone
+	Winners  int  // placings shown for the latest competition, default 3
+	Movers   int  // biggest climbers listed, default 3
+}
+
+// Latest returns the most recent competition, or nil if there are none
+func (s *Standings) Latest() *Competition {
+	comps := s.byDate()
+	if len(comps) == 0 {
+		return nil
+	}
+	for i := range s.Competitions {
+		if s.Competitions[i].Key == comps[len(comps)-1].Key {
+			return &s.Competitions[i]
+		}
+	}
+	return nil
+}
+
+// Before returns the standings recalculated without the competition key,
+// using the same options
+func (s *Standings) Before(key string) *Standings {
+	var comps []Competition
+	for _, comp := range s.Competitions {
+		if comp.Key != key {
+			comps = append(comps, comp)
+		}
+	}
+	return NewStandings(s.Year, comps, s.Options)
+}
+
+// Movement returns how many places the player has climbed since prev, and
+// false if they weren't ranked in the same section of prev
+func (s *Standings) Movement(prev *Standings, name string) (int, bool) {
+	p, ok := prev.OOMResults[name]
+	cur := s.OOMResults[name]
+	if !ok || p.Provisional != cur.Provisional {
+		return 0, false
+	}
+	return p.Rank - cur.Rank, true
+}
+
+// WriteText writes the standings as Markdown or plain text
+func (s *Standings) WriteText(w io.Writer, opts TextOptions) error {
+	if opts.Winners == 0 {
+		opts.Winners = 3
+	}
+	if opts.Movers == 0 {
+		opts.Movers = 3
+	}
+	latest := s.Latest()
+	var prev *Standings
+	heading := fmt.Sprintf("Order of Merit %d", s.Year)
+	if latest != nil {
+		prev = s.Before(latest.Key)
+		heading += fmt.Sprintf(" after %s (%s)", latest.Name, latest.Date)
+	}
+	if opts.Markdown {
+		fmt.Fprintf(w, "## %s\n\n", heading)
+	} else {
+		fmt.Fprintf(w, "%s\n%s\n\n", heading, strings.Repeat("=", utf8.RuneCountInString(heading)))
+	}
+	if incomplete := s.Incomplete(); incomplete != "" {
+		if opts.Markdown {
+			incomplete = "**" + incomplete + "**"
+		}
+		fmt.Fprintf(w, "%s\n\n", incomplete)
+	}
+
+	if latest != nil {
+		var placings []string
+		for _, r := range latest.byRank() {
+			if r.Rank > opts.Winners {
+				break
+			}
+			placings = append(placings, fmt.Sprintf("%s %s %s", ordinal(r.Rank), r.Name, r.Result))
+		}
+		if len(placings) > 0 {
+			fmt.Fprintf(w, "%s: %s\n\n", latest.Name, strings.Join(placings, ", "))
+		}
+	}
+
+	tieBreaks := len(s.Options.TieBreakers) > 0
+	rows := [][]string{{"Rank", "Name", "Points", "Played", "Move"}}
+	if tieBreaks {
+		rows[0] = append(rows[0], "Tie-break")
+	}
+	names := s.RankedPlayers
+	if opts.Top > 0 && len(names) > opts.Top {
+		names = names[:opts.Top]
+	}
+	for _, name := range names {
+		p := s.OOMResults[name]
+		move := ""
+		if prev != nil {
+			move = "new"
+			if n, ok := s.Movement(prev, name); ok {
+				move = formatMove(n)
+			}
+		}
+		row := []string{p.RankString(), p.Name,
+			fmt.Sprintf("%d", p.OOMPoints), fmt.Sprintf("%d", p.NumCompetitions), move}
+		if tieBreaks {
+			row = append(row, p.TieBreak.String())
+		}
+		rows = append(rows, row)
+	}
+	writeTable(w, rows, opts.Markdown, 1, 5)
+
+	if prev != nil {
+		if climbers := s.climbers(prev, opts.Movers); len(climbers) > 0 {
+			fmt.Fprintf(w, "\nBiggest climbers: %s\n", strings.Join(climbers, ", "))
+		}
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// climbers returns up to n of the players who climbed the most places
+// since prev e.g. "Ann +4"
+func (s *Standings) climbers(prev *Standings, n int) []string {
+	type climb struct {
+		name string
+		by   int
+	}
+	var cs []climb
+	for _, name := range s.RankedPlayers {
+		if by, ok := s.Movement(prev, name); ok && by > 0 {
+			cs = append(cs, climb{name, by})
+		}
+	}
+	sort.SliceStable(cs, func(i, j int) bool { return cs[i].by > cs[j].by })
+	var ret []string
+	for i := 0; i < len(cs) && i < n; i++ {
+		ret = append(ret, fmt.Sprintf("%s %s", cs[i].name, formatMove(cs[i].by)))
+	}
+	return ret
+}
+
+// formatMove returns +2, -1 or = for places climbed
+func formatMove(n int) string {
+	switch {
+	case n > 0:
+		return fmt.Sprintf("+%d", n)
+	case n < 0:
+		return fmt.Sprintf("%d", n)
+	}
+	return "="
+}
+
+// writeTable writes rows, the first being the header, as a Markdown table
+// or as space padded columns.  Columns are right aligned except those
+// listed in left
+func writeTable(w io.Writer, rows [][]string, markdown bool, left ...int) {
+	isLeft := make(map[int]bool)
+	for _, c := range left {
+		isLeft[c] = true
+	}
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for c, cell := range row {
+			if n := utf8.RuneCountInString(cell); n > widths[c] {
+				widths[c] = n
+			}
+		}
+	}
+	pad := func(s string, c int) string {
+		fill := strings.Repeat(" ", widths[c]-utf8.RuneCountInString(s))
+		if isLeft[c] {
+			return s + fill
+		}
+		return fill + s
+	}
+	for r, row := range rows {
+		var cells []string
+		for c, cell := range row {
+			cells = append(cells, pad(cell, c))
+		}
+		if markdown {
+			fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+		} else {
+			fmt.Fprintln(w, strings.TrimRight(strings.Join(cells, "  "), " "))
+		}
+		if r == 0 {
+			var rules []string
+			for c, width := range widths {
+				rule := strings.Repeat("-", width)
+				if markdown && !isLeft[c] {
+					rule = rule[1:] + ":"
+				}
+				rules = append(rules, rule)
+			}
+			if markdown {
+				fmt.Fprintf(w, "| %s |\n", strings.Join(rules, " | "))
+			} else {
+				fmt.Fprintln(w, strings.Join(rules, "  "))
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package oom
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	comps := []Competition{
+		testComp("1", "Sat 2nd Apr '16", "Ann", "Bea", "Cat"),
+		testComp("2", "Sat 9th Apr '16", "Cat", "Dot", "Bea"),
+	}
+	s := NewStandings(2016, comps, Options{})
+	var buf bytes.Buffer
+	if err := s.WriteText(&buf, TextOptions{Top: 3, Winners: 2}); err != nil {
+		t.Fatal(err)
+	}
+	want := `Order of Merit 2016 after comp 2 (Sat 9th Apr '16)
+==================================================
+
+comp 2: 1st Cat 36, 2nd Dot 36
+
+Rank  Name  Points  Played  Move
+----  ----  ------  ------  ----
+   1  Cat        4       2    +2
+  T2  Ann        3       1    -1
+  T2  Bea        3       2     =
+
+Biggest climbers: Cat +2
+
+`
+	if buf.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, buf.String())
+	}
+
+	s.Missing = []Competition{{Key: "3", Label: "elmstead"}}
+	buf.Reset()
+	s.WriteText(&buf, TextOptions{Markdown: true, Top: 1})
+	if !bytes.Contains(buf.Bytes(), []byte("\n**Incomplete - missing elmstead (3)**\n")) {
+		t.Errorf("expected the standings marked incomplete in\n%s", buf.String())
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("| Rank | Name | Points | Played | Move |\n"+
+		"| ---: | ---- | -----: | -----: | ---: |\n"+
+		"|    1 | Cat  |      4 |      2 |   +2 |\n")) {
+		t.Errorf("expected markdown table, got\n%s", buf.String())
+	}
+
+	// Bea has played more than Ann, separating them
+	s = NewStandings(2016, comps, Options{TieBreakers: []TieBreaker{MostPlayed}})
+	buf.Reset()
+	s.WriteText(&buf, TextOptions{Top: 3})
+	if !bytes.Contains(buf.Bytes(), []byte("Rank  Name  Points  Played  Move  Tie-break\n"+
+		"----  ----  ------  ------  ----  ---------\n"+
+		"   1  Cat        4       2    +2\n"+
+		"   2  Bea        3       2     =  played\n"+
+		"   3  Ann        3       1    -2  played\n")) {
+		t.Errorf("expected the tie-breaks shown, got\n%s", buf.String())
+	}
+}
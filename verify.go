@@ -0,0 +1,175 @@
+package oom
+
+// verify.go checks cached competitions against their results on the
+// website, as the club may correct a scorecard or disqualify a player after
+// the results were cached.  Players whose cached results were edited by
+// hand are marked by "# override: NAME" lines at the top of the cached
+// file, and their differences are reported but never applied
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// ResultChange is a difference in a player's result between the cache and
+// the website
+type ResultChange struct {
+	Name       string `json:"name"`
+	Field      string `json:"field"` // added, removed, rank, result, points or id
+	Cached     string `json:"cached"`
+	Published  string `json:"published"`
+	Overridden bool   `json:"overridden,omitempty"` // the cached result was edited by hand
+}
+
+// Verification compares a cached competition with its published results
+type Verification struct {
+	Cached    Competition    `json:"cached"`
+	Published Competition    `json:"published"`
+	Changes   []ResultChange `json:"changes"`
+	client    *Client        // whose cache Apply updates
+}
+
+// Verify refetches the results of the cached competition comp.Key and
+// compares them with the cache, field by field
+func (c *Client) Verify(ctx context.Context, comp Competition) (*Verification, error) {
+	if c.Offline {
+		return nil, errors.New("oom: can't verify competitions offline")
+	}
+	cached := Competition{Key: comp.Key}
+	if ok, err := c.readCached(&cached); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrNotCached
+	}
+	if cached.URL == "" {
+		cached.URL = comp.URL
+	}
+	published := Competition{Key: cached.Key, Name: cached.Name, Date: cached.Date,
+		URL: cached.URL, Major: comp.Major, Label: comp.Label}
+	if err := c.populateResultsFromWeb(ctx, &published); err != nil {
+		return nil, err
+	}
+	if published.NumPlayers == 0 {
+		return nil, fmt.Errorf("oom: no results for competition %s on the website, not verifying it", cached.Key)
+	}
+	v := &Verification{Cached: cached, Published: published, Changes: []ResultChange{}, client: c}
+	overridden := make(map[string]bool)
+	for _, name := range cached.Overrides {
+		overridden[name] = true
+	}
+	rank := make(map[string]int)
+	for name, r := range cached.Results {
+		rank[name] = r.Rank
+	}
+	for name, r := range published.Results {
+		rank[name] = r.Rank
+	}
+	var names []string
+	for name := range rank {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if rank[names[i]] != rank[names[j]] {
+			return rank[names[i]] < rank[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		cr, inCache := cached.Results[name]
+		pr, onWeb := published.Results[name]
+		change := func(field string, was string, now string) {
+			v.Changes = append(v.Changes, ResultChange{Name: name, Field: field, Cached: was,
+				Published: now, Overridden: overridden[name]})
+		}
+		switch {
+		case !onWeb:
+			change("removed", cr.Result, "")
+		case !inCache:
+			change("added", "", pr.Result)
+		default:
+			if cr.Rank != pr.Rank {
+				change("rank", strconv.Itoa(cr.Rank), strconv.Itoa(pr.Rank))
+			}
+			if cr.Result != pr.Result {
+				change("result", cr.Result, pr.Result)
+			}
+			if cr.OOMPoints != pr.OOMPoints {
+				change("points", strconv.Itoa(cr.OOMPoints), strconv.Itoa(pr.OOMPoints))
+			}
+			if cr.ID != "" && pr.ID != "" && cr.ID != pr.ID {
+				change("id", cr.ID, pr.ID)
+			}
+		}
+	}
+	return v, nil
+}
+
+// Changed returns true if there are differences to apply, those of
+// overridden players aside
+func (v *Verification) Changed() bool {
+	for _, c := range v.Changes {
+		if !c.Overridden {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes the differences, a line per change
+func (v *Verification) WriteText(w io.Writer) error {
+	title := fmt.Sprintf("%s %s (%s)", v.Cached.Key, v.Cached.Name, v.Cached.Date)
+	if len(v.Changes) == 0 {
+		_, err := fmt.Fprintf(w, "%s: unchanged\n", title)
+		return err
+	}
+	fmt.Fprintf(w, "%s: %s\n", title, plural(len(v.Changes), "change"))
+	if v.Cached.NumPlayers != v.Published.NumPlayers {
+		fmt.Fprintf(w, "  players: %d -> %d\n", v.Cached.NumPlayers, v.Published.NumPlayers)
+	}
+	for _, c := range v.Changes {
+		kept := ""
+		if c.Overridden {
+			kept = " (override, kept)"
+		}
+		switch c.Field {
+		case "added":
+			fmt.Fprintf(w, "  %s: added, %s%s\n", c.Name, c.Published, kept)
+		case "removed":
+			fmt.Fprintf(w, "  %s: removed, was %s%s\n", c.Name, c.Cached, kept)
+		default:
+			fmt.Fprintf(w, "  %s: %s %s -> %s%s\n", c.Name, c.Field, c.Cached, c.Published, kept)
+		}
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// Apply updates the cache with the published results, keeping the cached
+// results of the overridden players and the comments at the top of the
+// cached file.  It refuses to replace the cached results with a page of no
+// results, as served while the website is being updated
+func (v *Verification) Apply() error {
+	if v.Published.NumPlayers == 0 {
+		return fmt.Errorf("oom: no results for competition %s on the website, not applying them", v.Cached.Key)
+	}
+	merged := v.Published
+	merged.Name, merged.Date, merged.URL = v.Cached.Name, v.Cached.Date, v.Cached.URL
+	merged.Overrides, merged.notes = v.Cached.Overrides, v.Cached.notes
+	merged.Results = make(map[string]PlayerResult)
+	for name, r := range v.Published.Results {
+		merged.Results[name] = r
+	}
+	for _, name := range v.Cached.Overrides {
+		if r, ok := v.Cached.Results[name]; ok {
+			merged.Results[name] = r
+		} else {
+			delete(merged.Results, name)
+		}
+	}
+	merged.NumPlayers = len(merged.Results)
+	return v.client.saveComp(&merged)
+}
@@ -0,0 +1,102 @@
+package oom
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	c := newTestClient(t)
+	c.Retries = 0
+	var empty atomic.Bool // serving a page of no results
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if empty.Load() {
+			fmt.Fprint(w, "<table></table>")
+			return
+		}
+		fmt.Fprint(w, compPage+`<tr><td><a href="player.php?playerid=103">Cat Cole</a>(30)</td>
+<td><a href="viewround.php?roundid=3">NR</a></td></tr>`)
+	}))
+	defer ts.Close()
+	// Ann's result was corrected from 36 to 38 on the website, Bea's
+	// edited by hand, Dot disqualified and Cat added
+	cached := "# Comments\r\n# override: Bea Baker\r\n" +
+		"key, 777\r\nname, Spring Medal\r\ndate, Sat 9th Apr '16\r\n" +
+		"url, " + ts.URL + "/competition.php?compid=777\r\nnumber of players, 3\r\n" +
+		"oom_points, rank_in_comp, igresult, name, playerid - row per player\r\n" +
+		"3, 1, 36, Ann Able, 101\r\n2, 2, 34, Bea Baker, 102\r\n1, 3, 30, Dot Dean, 104\r\n"
+	fname := filepath.Join(c.CacheDir, "777.txt")
+	ioutil.WriteFile(fname, []byte(cached), 0644)
+
+	v, err := c.Verify(context.Background(), Competition{Key: "777"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	v.WriteText(&b)
+	want := `777 Spring Medal (Sat 9th Apr '16): 4 changes
+  Ann Able: result 36 -> 38
+  Bea Baker: result 34 -> 35 (override, kept)
+  Cat Cole: added, NR
+  Dot Dean: removed, was 30
+
+`
+	if b.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, b.String())
+	}
+	if !v.Changed() {
+		t.Errorf("expected changes to apply")
+	}
+
+	if err := v.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	comp := Competition{Key: "777"}
+	if ok, err := c.LoadCached(&comp); !ok || err != nil {
+		t.Fatal("expected 777 cached")
+	}
+	if comp.NumPlayers != 3 {
+		t.Errorf("expected 3 players, got %d", comp.NumPlayers)
+	}
+	if comp.Results["Ann Able"].Result != "38" || comp.Results["Bea Baker"].Result != "34" ||
+		comp.Results["Cat Cole"].Result != "NR" || len(comp.Results) != 3 {
+		t.Errorf("expected Ann's correction applied and Bea's override kept, got %v", comp.Results)
+	}
+	data, _ := ioutil.ReadFile(fname)
+	if !strings.HasPrefix(string(data), "# Comments\r\n# override: Bea Baker\r\n") {
+		t.Errorf("expected the comments kept, got %q", data)
+	}
+
+	v, err = c.Verify(context.Background(), Competition{Key: "777"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Changed() || len(v.Changes) != 1 {
+		t.Errorf("expected only Bea's override left, got %v", v.Changes)
+	}
+	if _, err := c.Verify(context.Background(), Competition{Key: "999"}); err != ErrNotCached {
+		t.Errorf("expected ErrNotCached, got %v", err)
+	}
+
+	// the results taken down while the website is updated
+	empty.Store(true)
+	if _, err := c.Verify(context.Background(), Competition{Key: "777"}); err == nil {
+		t.Errorf("no results: expected an error")
+	}
+	v.Published = Competition{Key: "777"}
+	if err := v.Apply(); err == nil {
+		t.Errorf("no results: expected Apply to refuse")
+	}
+	comp = Competition{Key: "777"}
+	if ok, err := c.LoadCached(&comp); !ok || err != nil || len(comp.Results) != 3 {
+		t.Errorf("no results: expected the cache kept, got %v", comp.Results)
+	}
+}
@@ -0,0 +1,78 @@
+package oom
+
+// watch.go polls the website for competitions in the series as they are
+// published, so the standings can be updated without anyone noticing the
+// competition and adding it to oom.conf - see the "match" lines described
+// in competition.go
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Watcher polls the year's list of competitions for those in the series,
+// listed by key or matched by name in Config
+type Watcher struct {
+	Client *Client
+	Year   int
+	Config string // oom.conf
+	All    bool   // every competition in the year is in the series
+}
+
+// Poll refetches the year's list of competitions, loads those in the
+// series that are new or newly completed and returns them.  A competition
+// is newly completed when its results are published, having been listed,
+// or even cached, with no players beforehand.  Competitions dated in the
+// future are left for a later poll, as are those that fail to load.  An
+// error fetching the list of competitions is returned, for the caller to
+// poll again later
+func (w *Watcher) Poll() ([]Competition, error) {
+	if w.Client.Offline {
+		return nil, errors.New("oom: can't watch for competitions offline")
+	}
+	// so the descriptions are up to date
+	if _, _, err := w.Client.fetchAllCompsPage(w.Year, false); err != nil {
+		return nil, err
+	}
+	var comps []Competition
+	var err error
+	if w.All {
+		comps, err = w.Client.FetchAllCompDesc(w.Year)
+	} else {
+		comps, err = w.Client.FetchCompDescriptions(w.Year, w.Config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var ret []Competition
+	for i := range comps {
+		comp := &comps[i]
+		if t := comp.Time(); !t.IsZero() && t.After(now) {
+			continue
+		}
+		cached, err := w.Client.readCached(comp)
+		if err != nil {
+			Logger.Warn("reading cached competition", "comp", comp.Title(), "err", err)
+			continue // leave the file as it is
+		}
+		if cached && comp.NumPlayers > 0 {
+			continue
+		}
+		if err := w.Client.populateResultsFromWeb(context.Background(), comp); err != nil {
+			Logger.Warn("loading competition", "comp", comp.Title(), "err", err)
+			continue // try again next poll
+		}
+		if comp.NumPlayers == 0 {
+			continue // played but the results aren't published yet
+		}
+		Logger.Info("new results", "comp", comp.Title(), "players", comp.NumPlayers)
+		if err := w.Client.saveComp(comp); err != nil {
+			Logger.Warn("caching competition", "comp", comp.Title(), "err", err)
+			continue // try again next poll
+		}
+		ret = append(ret, *comp)
+	}
+	return ret, nil
+}
@@ -1,13 +1,26 @@
 package oom
 
-// webfunc.go provides a single public function
-// MustFetch(url string) data[] byte
-// The package level client need not be accessed outside this file
+// webfunc.go provides a single public method
+// Client.MustFetch(url string) data[] byte
+// The client's http.Client need not be accessed outside this file
+//
+// The session cookies are kept in the client's SessionFile between runs,
+// so a run only logs in when the session has expired.  Every page fetched
+// is checked for the login page being served in its place, in which case
+// the client logs in again, once, before fetching the page again
+//
+// Requests are spaced RequestInterval apart for each host, so as not to be
+// throttled by the club's small server, and retried with the delay
+// doubling each time on timeouts, dropped connections, 5xx responses and
+// 429 Too Many Requests - the login's POST only on 429 and 503, as the
+// server may have acted on it otherwise
 
 import (
-	"bufio"
+	"context"
+	"encoding/json"
+	"io"
 	"io/ioutil"
-	"log"
+	"net"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
@@ -15,131 +28,323 @@ import (
 	//"golang.org/x/net/publicsuffix"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
-	"sync"
+	"syscall"
+	"time"
 )
 
-// single http client (will be set to valid logged in client on first call)
-var client *http.Client
-var mutex = &sync.Mutex{}
-
-// MustFetch returns a byteslice for the given page or dies.  It invokes
-// login() the first time it is called to set package variable client
-// where login() reads email and pin from creds.conf by default or from stdin
-func MustFetch(urlString string) (data []byte) {
-	mutex.Lock()
-	if client == nil {
-		if err := login(); err != nil {
-			log.Fatal(err)
+// defaultLoginURL is the website's login page
+const defaultLoginURL = "https://www.colchestergolfclub.com/login.php"
+
+// loginRequired marks the login page, served in place of the page asked
+// for when not logged in
+const loginRequired = "<title>Login Required"
+
+// errLoginRequired is returned by fetchPage when served the login page
+var errLoginRequired = errors.New("login required")
+
+// MustFetch returns a byteslice for the given page or dies, logging the
+// error to Logger, see fetch
+func (c *Client) MustFetch(urlString string) (data []byte) {
+	if c.Offline {
+		Logger.Error("offline, not fetching", "url", urlString)
+		os.Exit(1)
+	}
+	data, err := c.fetch(context.Background(), urlString)
+	if err != nil {
+		Logger.Error("fetching", "url", urlString, "err", err)
+		os.Exit(1)
+	}
+	return data
+}
+
+// fetch returns the page, using the session saved in SessionFile if any.
+// If asked to log in, it invokes login() and tries once more.  Cancelling
+// ctx abandons the request, and any retries
+func (c *Client) fetch(ctx context.Context, urlString string) ([]byte, error) {
+	c.mutex.Lock()
+	if c.http == nil {
+		if err := c.newHTTP(); err != nil {
+			c.mutex.Unlock()
+			return nil, err
 		}
 	}
-	mutex.Unlock()
-	return fetchPage(urlString)
+	gen := c.loginGen
+	c.mutex.Unlock()
+	data, err := c.fetchPage(ctx, urlString)
+	if err != errLoginRequired {
+		return data, err
+	}
+	if err := c.relogin(gen); err != nil {
+		return nil, err
+	}
+	data, err = c.fetchPage(ctx, urlString)
+	if err == errLoginRequired {
+		return nil, fmt.Errorf("%s still asks to log in after logging in - check credentials?", urlString)
+	}
+	return data, err
 }
 
-func fetchPage(urlString string) (data []byte) {
-	log.Println("fetching page ", urlString)
-	u, err := url.Parse(urlString)
+// relogin logs in again unless another goroutine has tried to since login
+// generation gen, in which case it returns the error of that attempt - so
+// goroutines waiting on a failed login don't each try again with the same
+// credentials
+func (c *Client) relogin(gen int) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.loginGen != gen {
+		return c.loginErr
+	}
+	c.loginErr = c.login()
+	c.loginGen++
+	return c.loginErr
+}
+
+// fetchPage returns the page, or errLoginRequired if served the login page
+// instead.  A page kept from before, see pages.go, is only downloaded again
+// if it has changed
+func (c *Client) fetchPage(ctx context.Context, urlString string) ([]byte, error) {
+	Logger.Debug("fetching page", "url", urlString)
+	kept, header := c.keptPage(urlString)
+	resp, err := c.send(ctx, "GET", urlString, nil, header)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	if resp.status == http.StatusNotModified {
+		if kept == nil {
+			return nil, fmt.Errorf("%s: server returned 304 Not Modified to an unconditional GET", urlString)
+		}
+		Logger.Debug("page not modified", "url", urlString)
+		c.pageNotModified(urlString)
+		return kept, nil
+	}
+	if strings.Contains(string(resp.body), loginRequired) {
+		return nil, errLoginRequired
 	}
-	resp, err := client.Get(u.String())
+	c.keepPage(urlString, resp)
+	return resp.body, nil
+}
+
+// response is what send returns of a 200 or 304 Not Modified response
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// send makes the request, a POST of form if not nil, with the header, and
+// returns the response.  It waits its turn for the host and retries
+// failures that may pass, see retryable
+func (c *Client) send(ctx context.Context, method string, urlString string, form url.Values, header http.Header) (response, error) {
+	u, err := url.Parse(urlString)
 	if err != nil {
-		log.Fatal(err)
+		return response{}, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		log.Fatal("Server returned non-200 status: %v\n", resp.Status)
+	delay := c.Backoff
+	for attempt := 0; ; attempt++ {
+		if err := c.waitTurn(ctx, u.Host); err != nil {
+			return response{}, err
+		}
+		var body io.Reader
+		if form != nil {
+			body = strings.NewReader(form.Encode())
+		}
+		req, err := http.NewRequestWithContext(ctx, method, urlString, body)
+		if err != nil {
+			return response{}, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req.Header.Set("User-Agent", c.UserAgent)
+		if form != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		resp, wait, err := c.do(req)
+		if err == nil || attempt >= c.Retries || !retryable(err) || ctx.Err() != nil {
+			return resp, err
+		}
+		if method == "POST" && !refused(err) {
+			return resp, err // the server may have acted on it
+		}
+		if wait < delay {
+			wait = delay
+		}
+		Logger.Warn("request failed, retrying", "err", err, "wait", wait)
+		if err := sleep(ctx, wait); err != nil {
+			return response{}, err
+		}
+		delay *= 2
 	}
-	data, err = ioutil.ReadAll(resp.Body)
+}
+
+// do makes the request, returning a 200 or 304 response, or an error and
+// how long the server asked to be left for in Retry-After
+func (c *Client) do(req *http.Request) (response, time.Duration, error) {
+	resp, err := c.http.Do(req)
 	if err != nil {
-		log.Fatal(err)
+		return response{}, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotModified {
+		io.Copy(ioutil.Discard, resp.Body)
+		secs, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return response{}, time.Duration(secs) * time.Second, httpStatusError{req.URL.String(), resp.StatusCode}
 	}
-	resp.Body.Close()
-	return
+	data, err := ioutil.ReadAll(resp.Body)
+	return response{resp.StatusCode, resp.Header, data}, 0, err
 }
 
-// login sets package level *http.Client client to a valid logged in client
-// to www.colchestergolfclub.com or returns an error.
-// Credentials are read from file creds.conf or (if missing) from Stdin
-// The returned page is checked for string "<title>Login Required" which if
-// found indicates a failed login
-func login() error {
-	//log.Println("logging in...")
+// httpStatusError is a non-200 response
+type httpStatusError struct {
+	url    string
+	status int
+}
+
+func (e httpStatusError) Error() string {
+	return fmt.Sprintf("%s: server returned non-200 status: %d %s", e.url, e.status, http.StatusText(e.status))
+}
+
+// retryable returns true for errors that may pass: timeouts, dropped
+// connections, server errors and being asked to slow down
+func retryable(err error) bool {
+	var se httpStatusError
+	if errors.As(err, &se) {
+		return se.status >= 500 || se.status == http.StatusTooManyRequests
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// refused returns true if the server turned the request away without
+// acting on it, 429 Too Many Requests or 503 Service Unavailable, so that
+// even a POST can be retried
+func refused(err error) bool {
+	var se httpStatusError
+	return errors.As(err, &se) && (se.status == http.StatusTooManyRequests || se.status == http.StatusServiceUnavailable)
+}
+
+// waitTurn sleeps until RequestInterval after the last request to host
+func (c *Client) waitTurn(ctx context.Context, host string) error {
+	c.hostMutex.Lock()
+	now := time.Now()
+	next := c.nextRequest[host]
+	if next.Before(now) {
+		next = now
+	}
+	c.nextRequest[host] = next.Add(c.RequestInterval)
+	c.hostMutex.Unlock()
+	return sleep(ctx, next.Sub(now))
+}
+
+// sleep sleeps for d, or until ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// newHTTP sets the client's http.Client to one with the session cookies
+// saved in SessionFile, if any
+func (c *Client) newHTTP() error {
 	options := cookiejar.Options{
 	//PublicSuffixList: publicsuffix.List,
 	}
 	jar, err := cookiejar.New(&options)
 	if err != nil {
-		log.Println(err)
 		return err
 	}
+	c.http = &http.Client{Jar: jar, Timeout: c.Timeout}
+	if c.SessionFile == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(c.SessionFile)
+	if err != nil {
+		return nil // no saved session
+	}
+	var cookies []*http.Cookie
+	if err := json.Unmarshal(data, &cookies); err != nil {
+		Logger.Warn("ignoring saved session", "file", c.SessionFile, "err", err)
+		return nil
+	}
+	u, err := url.Parse(c.loginURL)
+	if err != nil {
+		return err
+	}
+	jar.SetCookies(u, cookies)
+	return nil
+}
 
-	client = &http.Client{Jar: jar}
-	u, err := url.Parse("https://www.colchestergolfclub.com/login.php")
+// saveSession writes the session cookies to SessionFile
+func (c *Client) saveSession() error {
+	if c.SessionFile == "" {
+		return nil
+	}
+	u, err := url.Parse(c.loginURL)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(c.http.Jar.Cookies(u))
 	if err != nil {
-		log.Println(err)
 		return err
 	}
+	return ioutil.WriteFile(c.SessionFile, data, 0600)
+}
+
+// login logs the client's http.Client in to
+// www.colchestergolfclub.com, creating it if need be, or returns
+// an error.
+// Credentials are found by credentials(), see creds.go
+// The returned page is checked for string "<title>Login Required" which if
+// found indicates a failed login.  The session is saved in SessionFile
+func (c *Client) login() error {
+	//log.Println("logging in...")
+	if c.http == nil {
+		if err := c.newHTTP(); err != nil {
+			return err
+		}
+	}
 
 	// first call to Get sets the session id - but not logged in yet
-	resp, err := client.Get(u.String())
+	if _, err := c.send(context.Background(), "GET", c.loginURL, nil, nil); err != nil {
+		Logger.Error("login", "err", err)
+		return err
+	}
+
+	email, pin, source, err := c.credentials()
 	if err != nil {
-		log.Println(err)
 		return err
 	}
-	resp.Body.Close()
-
-	var email, pin string
-	// try to read credentials from file
-	f, err := os.Open("creds.conf")
-	if err == nil {
-		defer f.Close()
-		scanner := bufio.NewScanner(f)
-		scanner.Scan()
-		email = scanner.Text()
-		scanner.Scan()
-		pin = scanner.Text()
-	} else {
-		email, pin = readCredsStdin()
-	}
-	fmt.Printf("Logging in using <%s>, <%s>\n", email, pin)
-
-	// post the login data (discard returned page)
-	resp, err = client.PostForm(u.String(),
+	Logger.Info("logging in", "email", email, "credentials", source)
+
+	// post the login data
+	resp, err := c.send(context.Background(), "POST", c.loginURL,
 		url.Values{"task": {"login"}, "topmenu": {"1"},
 			"memberid": {email}, "pin": {pin},
-			"cachemid": {"1"}, "Submit": {"Login"}})
+			"cachemid": {"1"}, "Submit": {"Login"}}, nil)
 
 	if err != nil {
-		log.Println(err)
+		Logger.Error("login", "err", err)
 		return err
 	}
 
 	// check if the login OK
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatal(err)
-	}
-	page := string(data)
-	if strings.Index(page, "<title>Login Required") != -1 {
+	page := string(resp.body)
+	if strings.Index(page, loginRequired) != -1 {
 		//fmt.Println(page)
 		return errors.New("Login failed - check credentials?")
 	}
-
-	resp.Body.Close()
+	if err := c.saveSession(); err != nil {
+		Logger.Warn("saving session", "file", c.SessionFile, "err", err)
+	}
 	return nil
 }
-
-func readCredsStdin() (email string, pin string) {
-	//fmt.Println("readCredsStdin")
-	scanner := bufio.NewScanner(os.Stdin)
-	fmt.Print("Enter email: ")
-	scanner.Scan()
-	email = scanner.Text()
-	fmt.Print("Enter PIN: ")
-	scanner.Scan()
-	pin = scanner.Text()
-	//fmt.Printf("readCredsStdin email  <%s>, pin <%s>\n", email, pin)
-	//fmt.Println(pin)
-	return
-}
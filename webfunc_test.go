@@ -1,14 +1,264 @@
 package oom
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestLogin(t *testing.T) {
 	fmt.Println("TestLogin")
-	err := login()
+	err := NewClient().login()
 	if err != nil {
-		t.Errorf("Expected nil, got err", err)
+		t.Errorf("Expected nil, got err %v", err)
+	}
+}
+
+// testSite stands in for the club website, serving /competition.php only
+// to a logged in session
+func testSite(logins *int) *httptest.Server {
+	sessions := map[string]bool{}
+	n := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie("session")
+		if err != nil {
+			n++
+			c = &http.Cookie{Name: "session", Value: fmt.Sprint("s", n), Path: "/"}
+			http.SetCookie(w, c)
+		}
+		switch r.URL.Path {
+		case "/login.php":
+			if r.Method == "POST" {
+				if r.FormValue("memberid") != "jo@example.com" || r.FormValue("pin") != "1234" {
+					fmt.Fprint(w, "<html><head><title>Login Required</title></head></html>")
+					return
+				}
+				*logins++
+				sessions[c.Value] = true
+			}
+			fmt.Fprint(w, "<html><head><title>Welcome</title></head></html>")
+		case "/expire":
+			sessions = map[string]bool{}
+		default:
+			if !sessions[c.Value] {
+				fmt.Fprint(w, "<html><head><title>Login Required</title></head></html>")
+				return
+			}
+			fmt.Fprint(w, "results")
+		}
+	}))
+}
+
+func TestSession(t *testing.T) {
+	var logins int
+	ts := testSite(&logins)
+	defer ts.Close()
+	t.Setenv("OOM_EMAIL", "")
+	c := newTestClient(t)
+	c.loginURL = ts.URL + "/login.php"
+	ioutil.WriteFile(c.CredsFile, []byte("jo@example.com\n1234\n"), 0600)
+
+	page := ts.URL + "/competition.php?cid=1266"
+	check := func(what string, wantLogins int) {
+		data, err := c.fetch(context.Background(), page)
+		if err != nil {
+			t.Fatalf("%s: %v", what, err)
+		}
+		if string(data) != "results" {
+			t.Errorf("%s: expected results, got %q", what, data)
+		}
+		if logins != wantLogins {
+			t.Errorf("%s: expected %d logins, got %d", what, wantLogins, logins)
+		}
+	}
+	check("first fetch", 1)
+	fi, err := os.Stat(c.SessionFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("expected session file mode 0600, got %v", fi.Mode().Perm())
+	}
+	check("same client", 1)
+	c.http = nil // as a new run
+	check("saved session", 1)
+	c.http.Get(ts.URL + "/expire")
+	check("expired session", 2)
+
+	c.http = nil
+	os.Remove(c.SessionFile)
+	ioutil.WriteFile(c.CredsFile, []byte("jo@example.com\n4321\n"), 0600)
+	_, err = c.fetch(context.Background(), page)
+	if err == nil || !strings.Contains(err.Error(), "Login failed") {
+		t.Errorf("bad creds: expected login failed, got %v", err)
+	}
+}
+
+func TestFailedLogin(t *testing.T) {
+	const n = 4
+	var pages, logins int32
+	allServed := make(chan struct{}) // closed once every fetch has been asked to log in
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login.php" && r.Method == "POST" {
+			atomic.AddInt32(&logins, 1)
+			<-allServed
+		} else if r.URL.Path != "/login.php" && atomic.AddInt32(&pages, 1) == n {
+			close(allServed)
+		}
+		fmt.Fprint(w, "<html><head><title>Login Required</title></head></html>")
+	}))
+	defer ts.Close()
+	t.Setenv("OOM_EMAIL", "")
+	c := newTestClient(t)
+	c.loginURL = ts.URL + "/login.php"
+	ioutil.WriteFile(c.CredsFile, []byte("jo@example.com\n4321\n"), 0600)
+
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			_, err := c.fetch(context.Background(), fmt.Sprintf("%s/competition.php?cid=%d", ts.URL, i))
+			errs <- err
+		}(i)
+	}
+	for i := 0; i < n; i++ {
+		if err := <-errs; err == nil || !strings.Contains(err.Error(), "Login failed") {
+			t.Errorf("expected login failed, got %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&logins); n != 1 {
+		t.Errorf("expected 1 login attempt shared by the fetches, got %d", n)
+	}
+}
+
+// newTestClient returns a client for a test, with its cache, credentials
+// and session in an empty directory and no waiting between requests
+func newTestClient(t *testing.T) *Client {
+	dir := t.TempDir()
+	c := NewClient()
+	c.CacheDir = dir
+	c.CredsFile = filepath.Join(dir, "creds.conf")
+	c.NetrcFile = ""
+	c.EncryptedCredsFile = filepath.Join(dir, "creds.enc")
+	c.SessionFile = filepath.Join(dir, "session")
+	c.Timeout = time.Second
+	c.Backoff = time.Millisecond
+	c.RequestInterval = 0
+	return c
+}
+
+func TestRetry(t *testing.T) {
+	c := newTestClient(t)
+	var requests int
+	var agent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		agent = r.UserAgent()
+		switch {
+		case r.URL.Path == "/missing":
+			http.NotFound(w, r)
+		case r.URL.Path == "/broken":
+			http.Error(w, "broken", http.StatusInternalServerError)
+		case r.URL.Path == "/down", requests < 3:
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+		default:
+			fmt.Fprint(w, "results")
+		}
+	}))
+	defer ts.Close()
+
+	data, err := c.fetch(context.Background(), ts.URL+"/flaky")
+	if err != nil || string(data) != "results" {
+		t.Errorf("flaky: expected results, got %q %v", data, err)
+	}
+	if requests != 3 {
+		t.Errorf("flaky: expected 3 requests, got %d", requests)
+	}
+	if agent != c.UserAgent {
+		t.Errorf("expected User-Agent %q, got %q", c.UserAgent, agent)
+	}
+
+	requests = 0
+	_, err = c.fetch(context.Background(), ts.URL+"/down")
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("down: expected a 503 error, got %v", err)
+	}
+	if requests != 4 {
+		t.Errorf("down: expected 4 requests, got %d", requests)
+	}
+
+	requests = 0
+	_, err = c.fetch(context.Background(), ts.URL+"/missing")
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("missing: expected a 404 error, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("missing: expected 1 request, not retried, got %d", requests)
+	}
+
+	// a POST is only retried when the server turned it away
+	form := url.Values{"pin": {"1234"}}
+	requests = 0
+	_, err = c.send(context.Background(), "POST", ts.URL+"/down", form, nil)
+	if err == nil || requests != 4 {
+		t.Errorf("post down: expected 4 requests and an error, got %d %v", requests, err)
+	}
+	requests = 0
+	_, err = c.send(context.Background(), "POST", ts.URL+"/broken", form, nil)
+	if err == nil || requests != 1 {
+		t.Errorf("post broken: expected 1 request, not retried, and an error, got %d %v", requests, err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	c := newTestClient(t)
+	c.Timeout, c.Retries = 50*time.Millisecond, 1
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+		fmt.Fprint(w, "results")
+	}))
+	defer ts.Close()
+	data, err := c.fetch(context.Background(), ts.URL)
+	if err != nil || string(data) != "results" {
+		t.Errorf("expected results after a timeout, got %q %v", data, err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestRequestInterval(t *testing.T) {
+	c := newTestClient(t)
+	c.Retries, c.RequestInterval = 0, 50*time.Millisecond
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "results")
+	}))
+	defer ts.Close()
+	start := time.Now()
+	done := make(chan error)
+	for i := 0; i < 4; i++ {
+		go func() {
+			_, err := c.fetch(context.Background(), ts.URL)
+			done <- err
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expected 4 requests to take at least 150ms, took %v", elapsed)
 	}
 }
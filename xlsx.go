@@ -0,0 +1,427 @@
+package oom
+
+// xlsx.go writes the standings as an Excel workbook, replacing the
+// committee spreadsheet that linked to out.csv by absolute path.  The
+// workbook is built directly with archive/zip so there is nothing extra
+// to install.  It contains
+// - Standings: key, date and name header rows (frozen with the rank and
+//   name columns) then a row per player.  Counted scores are shaded green
+//   and dropped scores grey by conditional formatting rules reading the
+//   Counted sheet, as which scores count depends on the options - best N,
+//   per period, majors - and can't be worked out by a cell formula
+// - a sheet per competition with the full results
+// - Info: season, points scheme and when the workbook was generated
+// - Counted: hidden, 1 for each score counted and 0 for each dropped, in
+//   the same cells as the scores in Standings
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cell styles, indexes in to cellXfs in xlsxStyles
+const (
+	styleNone = iota
+	styleBold
+)
+
+// conditional formats, indexes in to dxfs in xlsxStyles
+const (
+	dxfCounted = iota
+	dxfDropped
+)
+
+// xlsxCell is a single cell, either an inline string or a number
+type xlsxCell struct {
+	value  string
+	number bool
+	style  int
+}
+
+func xlsxString(s string, style int) xlsxCell {
+	return xlsxCell{value: s, style: style}
+}
+
+func xlsxNumber(n int, style int) xlsxCell {
+	return xlsxCell{value: strconv.Itoa(n), number: true, style: style}
+}
+
+// xlsxRule is a conditional format, applying the dxf to the cells in
+// sqref where formula, relative to the top left cell, is true
+type xlsxRule struct {
+	sqref   string
+	formula string
+	dxf     int
+}
+
+// xlsxSheet is a worksheet to be written to the workbook
+type xlsxSheet struct {
+	name       string
+	rows       [][]xlsxCell
+	freezeRows int
+	freezeCols int
+	widths     map[int]float64 // keyed by 0 based column
+	rules      []xlsxRule
+	hidden     bool
+}
+
+// WriteXLSX writes the standings as an xlsx workbook
+func (s *Standings) WriteXLSX(w io.Writer) error {
+	standings, counted := s.standingsSheets()
+	sheets := []xlsxSheet{standings}
+	used := map[string]bool{standings.name: true, "Info": true, counted.name: true}
+	for _, comp := range s.Competitions {
+		sheet := compSheet(comp)
+		sheet.name = uniqueSheetName(comp.Key+" "+comp.Name, used)
+		sheets = append(sheets, sheet)
+	}
+	sheets = append(sheets, s.infoSheet(time.Now()), counted)
+	return writeWorkbook(w, sheets)
+}
+
+// standingsSheets returns the Standings sheet and the hidden Counted sheet
+// its shading reads
+func (s *Standings) standingsSheets() (xlsxSheet, xlsxSheet) {
+	sheet := xlsxSheet{name: "Standings", freezeRows: 3, freezeCols: 2,
+		widths: map[int]float64{1: 24}}
+	counted := xlsxSheet{name: "Counted", hidden: true}
+	labels := []string{"rank", "name", "oomPts", "#Comp"}
+	keys := make([]xlsxCell, len(labels))
+	dates := make([]xlsxCell, len(labels))
+	var names []xlsxCell
+	for _, label := range labels {
+		names = append(names, xlsxString(label, styleBold))
+	}
+	for _, comp := range s.Competitions {
+		keys = append(keys, xlsxString(comp.Key, styleBold))
+		dates = append(dates, xlsxString(comp.Date, styleBold))
+		names = append(names, xlsxString(comp.Name, styleBold))
+	}
+	names = append(names, xlsxString("toImprove", styleBold))
+	if len(s.Options.TieBreakers) > 0 {
+		names = append(names, xlsxString("tieBreak", styleBold))
+	}
+	sheet.rows = append(sheet.rows, keys, dates, names)
+	counted.rows = append(counted.rows, keys)
+	counted.rows = append(counted.rows, make([][]xlsxCell, 2)...)
+	addRow := func(player string) {
+		row, flags := s.xlsxRow(s.OOMResults[player])
+		sheet.rows = append(sheet.rows, row)
+		counted.rows = append(counted.rows, flags)
+	}
+	for _, player := range s.RankedPlayers {
+		addRow(player)
+	}
+	if len(s.ProvisionalPlayers) > 0 {
+		sheet.rows = append(sheet.rows, nil, []xlsxCell{xlsxString(
+			fmt.Sprintf("Provisional - fewer than %d competitions", s.Options.MinComps),
+			styleBold)})
+		counted.rows = append(counted.rows, nil, nil)
+	}
+	for _, player := range s.ProvisionalPlayers {
+		addRow(player)
+	}
+	if len(s.Competitions) > 0 && len(sheet.rows) > 3 {
+		first := columnName(len(labels))
+		sqref := fmt.Sprintf("%s4:%s%d", first, columnName(len(labels)+len(s.Competitions)-1),
+			len(sheet.rows))
+		sheet.rules = []xlsxRule{
+			{sqref, fmt.Sprintf("Counted!%s4=1", first), dxfCounted},
+			{sqref, fmt.Sprintf("AND(ISNUMBER(Counted!%[1]s4),Counted!%[1]s4=0)", first), dxfDropped},
+		}
+	}
+	return sheet, counted
+}
+
+// xlsxRow returns the player's row in the Standings sheet and in the
+// Counted sheet, with 1 for each score counted and 0 for each dropped
+func (s *Standings) xlsxRow(p PlayerOOM) (row []xlsxCell, flags []xlsxCell) {
+	row = []xlsxCell{xlsxString(p.RankString(), styleNone),
+		xlsxString(p.Name, styleNone),
+		xlsxNumber(p.OOMPoints, styleBold),
+		xlsxNumber(p.NumCompetitions, styleNone)}
+	flags = []xlsxCell{{}, xlsxString(p.Name, styleNone), {}, {}}
+	for _, comp := range s.Competitions {
+		r, ok := p.PlayerByComp[comp.Key]
+		switch {
+		case !ok:
+			row = append(row, xlsxCell{})
+			flags = append(flags, xlsxCell{})
+		case r.Counted:
+			row = append(row, xlsxNumber(r.OOMPoints, styleNone))
+			flags = append(flags, xlsxNumber(1, styleNone))
+		default:
+			row = append(row, xlsxNumber(r.OOMPoints, styleNone))
+			flags = append(flags, xlsxNumber(0, styleNone))
+		}
+	}
+	row = append(row, xlsxNumber(p.ToImprove, styleNone))
+	if len(s.Options.TieBreakers) > 0 {
+		row = append(row, xlsxString(p.TieBreak.String(), styleNone))
+	}
+	return row, flags
+}
+
+// compSheet lists the full results of a competition, best first
+func compSheet(comp Competition) xlsxSheet {
+	sheet := xlsxSheet{freezeRows: 6, widths: map[int]float64{1: 24}}
+	sheet.rows = [][]xlsxCell{
+		{xlsxString("key", styleBold), xlsxString(comp.Key, styleNone)},
+		{xlsxString("name", styleBold), xlsxString(comp.Name, styleNone)},
+		{xlsxString("date", styleBold), xlsxString(comp.Date, styleNone)},
+		{xlsxString("url", styleBold), xlsxString(comp.URL, styleNone)},
+		{xlsxString("number of players", styleBold), xlsxNumber(comp.NumPlayers, styleNone)},
+		{xlsxString("position", styleBold), xlsxString("name", styleBold),
+			xlsxString("result", styleBold), xlsxString("oom points", styleBold)},
+	}
+	for _, r := range comp.byRank() {
+		sheet.rows = append(sheet.rows, []xlsxCell{xlsxNumber(r.Rank, styleNone),
+			xlsxString(r.Name, styleNone), xlsxString(r.Result, styleNone),
+			xlsxNumber(r.OOMPoints, styleNone)})
+	}
+	return sheet
+}
+
+// infoSheet describes how the standings were calculated
+func (s *Standings) infoSheet(generated time.Time) xlsxSheet {
+	sheet := xlsxSheet{name: "Info", widths: map[int]float64{0: 24, 1: 40}}
+	for _, line := range s.describe() {
+		sheet.rows = append(sheet.rows, []xlsxCell{xlsxString(line[0], styleBold),
+			xlsxString(line[1], styleNone)})
+	}
+	sheet.rows = append(sheet.rows, []xlsxCell{xlsxString("generated", styleBold),
+		xlsxString(generated.Format("Mon 2 Jan 2006 15:04"), styleNone)})
+	return sheet
+}
+
+// describe returns name, value pairs for the season and points scheme
+func (s *Standings) describe() [][2]string {
+	o := s.Options
+	all := func(n int) string {
+		if n <= 0 {
+			return "all"
+		}
+		return strconv.Itoa(n)
+	}
+	var tbs []string
+	for _, tb := range o.TieBreakers {
+		tbs = append(tbs, tb.String())
+	}
+	ret := [][2]string{
+		{"season", strconv.Itoa(s.Year)},
+		{"competitions", strconv.Itoa(len(s.Competitions))},
+		{"points", "field size - position + 1, none for NR, DQ and the like"},
+		{"shading", "green for scores counted, grey for scores dropped, as flagged in the hidden Counted sheet"},
+		{"scores counted", all(o.MaxComps)},
+		{"minimum to qualify", strconv.Itoa(o.MinComps)},
+		{"tie-breakers", strings.Join(tbs, ", ")},
+	}
+	if o.Period != NoPeriod && o.PerPeriod > 0 {
+		ret = append(ret, [2]string{"scores counted per " + o.Period.String(),
+			strconv.Itoa(o.PerPeriod)})
+	}
+	if o.MinMajors > 0 {
+		ret = append(ret, [2]string{"majors always counted", strconv.Itoa(o.MinMajors)})
+	}
+	if incomplete := s.Incomplete(); incomplete != "" {
+		ret = append(ret, [2]string{"incomplete", incomplete})
+	}
+	return ret
+}
+
+// uniqueSheetName returns name trimmed to a valid sheet name, at most 31
+// characters and not already used
+func uniqueSheetName(name string, used map[string]bool) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, name)
+	if len([]rune(name)) > 31 {
+		name = string([]rune(name)[:31])
+	}
+	base := []rune(name)
+	for n := 2; used[name]; n++ {
+		suffix := fmt.Sprintf(" %d", n)
+		if len(base)+len(suffix) > 31 {
+			base = base[:31-len(suffix)]
+		}
+		name = string(base) + suffix
+	}
+	used[name] = true
+	return name
+}
+
+// columnName returns the spreadsheet column for the 0 based index e.g. AB
+func columnName(n int) string {
+	name := ""
+	for n++; n > 0; n = (n - 1) / 26 {
+		name = string(rune('A'+(n-1)%26)) + name
+	}
+	return name
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func writeWorkbook(w io.Writer, sheets []xlsxSheet) error {
+	zw := zip.NewWriter(w)
+	files := map[string]string{
+		"[Content_Types].xml":        xlsxContentTypes(len(sheets)),
+		"_rels/.rels":                xlsxRootRels,
+		"xl/workbook.xml":            xlsxWorkbook(sheets),
+		"xl/_rels/workbook.xml.rels": xlsxWorkbookRels(len(sheets)),
+		"xl/styles.xml":              xlsxStyles,
+	}
+	for n, sheet := range sheets {
+		files[fmt.Sprintf("xl/worksheets/sheet%d.xml", n+1)] = sheet.xml()
+	}
+	// write in a fixed order so the same standings give the same file
+	names := []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml",
+		"xl/_rels/workbook.xml.rels", "xl/styles.xml"}
+	for n := range sheets {
+		names = append(names, fmt.Sprintf("xl/worksheets/sheet%d.xml", n+1))
+	}
+	for _, name := range names {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, files[name]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func (sheet xlsxSheet) xml() string {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0">`)
+	if sheet.freezeRows > 0 || sheet.freezeCols > 0 {
+		fmt.Fprintf(&b, `<pane xSplit="%d" ySplit="%d" topLeftCell="%s%d" activePane="bottomRight" state="frozen"/>`,
+			sheet.freezeCols, sheet.freezeRows, columnName(sheet.freezeCols), sheet.freezeRows+1)
+	}
+	b.WriteString(`</sheetView></sheetViews>`)
+	if len(sheet.widths) > 0 {
+		b.WriteString(`<cols>`)
+		for col := 0; col < 100; col++ {
+			if width, ok := sheet.widths[col]; ok {
+				fmt.Fprintf(&b, `<col min="%d" max="%d" width="%g" customWidth="1"/>`,
+					col+1, col+1, width)
+			}
+		}
+		b.WriteString(`</cols>`)
+	}
+	b.WriteString(`<sheetData>`)
+	for r, row := range sheet.rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := fmt.Sprintf("%s%d", columnName(c), r+1)
+			switch {
+			case cell.value == "":
+				if cell.style != styleNone {
+					fmt.Fprintf(&b, `<c r="%s" s="%d"/>`, ref, cell.style)
+				}
+			case cell.number:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, cell.style, cell.value)
+			default:
+				fmt.Fprintf(&b, `<c r="%s" s="%d" t="inlineStr"><is><t>%s</t></is></c>`,
+					ref, cell.style, xmlEscape(cell.value))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData>`)
+	for n, rule := range sheet.rules {
+		fmt.Fprintf(&b, `<conditionalFormatting sqref="%s"><cfRule type="expression" dxfId="%d" priority="%d"><formula>%s</formula></cfRule></conditionalFormatting>`,
+			rule.sqref, rule.dxf, n+1, xmlEscape(rule.formula))
+	}
+	b.WriteString(`</worksheet>`)
+	return b.String()
+}
+
+func xlsxContentTypes(numSheets int) string {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for n := 1; n <= numSheets; n++ {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+const xlsxRootRels = xml.Header +
+	`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+func xlsxWorkbook(sheets []xlsxSheet) string {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for n, sheet := range sheets {
+		state := ""
+		if sheet.hidden {
+			state = ` state="hidden"`
+		}
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d"%s r:id="rId%d"/>`,
+			xmlEscape(sheet.name), n+1, state, n+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+	return b.String()
+}
+
+func xlsxWorkbookRels(numSheets int) string {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for n := 1; n <= numSheets; n++ {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, numSheets+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+// xlsxStyles defines the cell styles in the order of the style constants,
+// none and bold, and the conditional formats in the order of the dxf
+// constants, counted (green fill) and dropped (grey italic on grey fill)
+const xlsxStyles = xml.Header +
+	`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2">` +
+	`<font><sz val="11"/><name val="Calibri"/></font>` +
+	`<font><b/><sz val="11"/><name val="Calibri"/></font>` +
+	`</fonts>` +
+	`<fills count="2">` +
+	`<fill><patternFill patternType="none"/></fill>` +
+	`<fill><patternFill patternType="gray125"/></fill>` +
+	`</fills>` +
+	`<borders count="1"><border/></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`</cellXfs>` +
+	`<dxfs count="2">` +
+	`<dxf><fill><patternFill patternType="solid"><bgColor rgb="FFC6EFCE"/></patternFill></fill></dxf>` +
+	`<dxf><font><i/><color rgb="FF808080"/></font><fill><patternFill patternType="solid"><bgColor rgb="FFEDEDED"/></patternFill></fill></dxf>` +
+	`</dxfs>` +
+	`</styleSheet>`
@@ -0,0 +1,74 @@
+package oom
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestWriteXLSX(t *testing.T) {
+	comps := []Competition{
+		testComp("1", "Sat 2nd Apr '16", "Ann", "Bea"),
+		testComp("2", "Sat 9th Apr '16", "Bea"),
+	}
+	comps[0].Name = "Fish & Chips [Day 1]"
+	s := NewStandings(2016, comps, Options{MaxComps: 1})
+	var buf bytes.Buffer
+	if err := s.WriteXLSX(&buf); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	for name, want := range map[string]string{
+		"xl/workbook.xml": `<sheet name="1 Fish &amp; Chips -Day 1-" sheetId="2" r:id="rId2"/>`,
+		// Bea's dropped score in the second competition column (F)
+		"xl/worksheets/sheet1.xml": `<c r="F5" s="0"><v>1</v></c>`,
+		"xl/worksheets/sheet4.xml": `<t>season</t>`,
+		// flagged dropped in the same cell of the hidden Counted sheet
+		"xl/worksheets/sheet5.xml": `<c r="F5" s="0"><v>0</v></c>`,
+	} {
+		if !strings.Contains(files[name], want) {
+			t.Errorf("%s: expected %s in\n%s", name, want, files[name])
+		}
+	}
+	if !strings.Contains(files["xl/workbook.xml"], `<sheet name="Counted" sheetId="5" state="hidden" r:id="rId5"/>`) {
+		t.Errorf("expected the Counted sheet hidden, got\n%s", files["xl/workbook.xml"])
+	}
+	if counted := files["xl/worksheets/sheet5.xml"]; !strings.Contains(counted, `<c r="E4" s="0"><v>1</v></c>`) ||
+		!strings.Contains(counted, `<c r="E5" s="0"><v>1</v></c>`) {
+		t.Errorf("expected Bea's and Ann's first scores flagged counted, got\n%s", counted)
+	}
+	for _, want := range []string{
+		`<conditionalFormatting sqref="E4:F5"><cfRule type="expression" dxfId="0" priority="1"><formula>Counted!E4=1</formula></cfRule></conditionalFormatting>`,
+		`<conditionalFormatting sqref="E4:F5"><cfRule type="expression" dxfId="1" priority="2"><formula>AND(ISNUMBER(Counted!E4),Counted!E4=0)</formula></cfRule></conditionalFormatting>`,
+	} {
+		if !strings.Contains(files["xl/worksheets/sheet1.xml"], want) {
+			t.Errorf("expected %s in\n%s", want, files["xl/worksheets/sheet1.xml"])
+		}
+	}
+	if !strings.Contains(files["xl/styles.xml"], `<dxfs count="2">`) {
+		t.Errorf("expected the counted and dropped formats, got\n%s", files["xl/styles.xml"])
+	}
+	if !strings.Contains(files["xl/worksheets/sheet4.xml"], `<t>field size - position + 1, none for NR, DQ and the like</t>`) {
+		t.Errorf("expected the points scheme in the info sheet, got\n%s", files["xl/worksheets/sheet4.xml"])
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for n, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(n); got != want {
+			t.Errorf("columnName(%d): expected %s, got %s", n, want, got)
+		}
+	}
+}